@@ -3,10 +3,12 @@ module comparer
 go 1.18
 
 require (
+	shared v0.0.0
 	github.com/astaxie/beego v1.12.3
 	github.com/aws/aws-sdk-go v1.44.297
 	github.com/caarlos0/env/v9 v9.0.0
 	github.com/gin-gonic/gin v1.9.1
+	github.com/google/uuid v1.3.0
 	github.com/hiyali/logli v0.0.0-20190425151209-5f4624646a54
 	github.com/joho/godotenv v1.5.1
 	github.com/json-iterator/go v1.1.12
@@ -46,3 +48,6 @@ require (
 	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// processor packages shared by the downloader, the comparer & the loader
+replace shared => ../shared
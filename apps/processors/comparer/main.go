@@ -2,6 +2,7 @@ package main
 
 import (
 	"comparer/pkg/config"
+	"comparer/pkg/job"
 	"comparer/pkg/logging"
 	"comparer/routers"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 func init() {
 	config.Setup()
 	logging.Setup()
+	job.Setup()
 }
 
 func main() {
@@ -3,6 +3,7 @@ package app
 import (
 	"github.com/astaxie/beego/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
 	"comparer/pkg/e"
 )
@@ -25,3 +26,22 @@ func BindAndValid(c *gin.Context, form interface{}) error {
 
 	return nil
 }
+
+// DecodeAndValid decodes json data and validates it like BindAndValid, used for the params of jobs
+func DecodeAndValid(data []byte, form interface{}) error {
+	err := binding.JSON.BindBody(data, form)
+	if err != nil {
+		return e.WrapInternalError(err, e.INVALID_PARAMS, "Error binding params")
+	}
+
+	valid := validation.Validation{}
+	check, err := valid.Valid(form)
+	if err != nil {
+		return e.WrapInternalError(err, e.ERROR, "Error validating params")
+	}
+	if !check {
+		return e.NewInternalErrorWithDescription(e.INVALID_PARAMS, "Invalid Params", valid.Errors[0].Error())
+	}
+
+	return nil
+}
@@ -11,4 +11,8 @@ const (
 	API_KEY_INVALID = 1004
 
 	COMPARE_ERROR = 10002
+
+	JOB_NOT_FOUND    = 1401
+	JOB_TYPE_INVALID = 1402
+	JOB_FINISHED     = 1403
 )
@@ -15,39 +15,40 @@ type ErrorResponse struct {
 	Description string    `json:"description"`
 }
 
+// NewErrorResponse maps the error to the http status & the response body
+func NewErrorResponse(err error) (int, ErrorResponse) {
+	var externalErr *ExternalError
+	var internalErr *InternalError
+	if errors.As(err, &externalErr) {
+		return http.StatusUnprocessableEntity, ErrorResponse{
+			Type:        ExternalErrorType,
+			Code:        externalErr.Code,
+			Msg:         externalErr.Msg,
+			Description: externalErr.Description,
+		}
+	} else if errors.As(err, &internalErr) {
+		return http.StatusInternalServerError, ErrorResponse{
+			Type:        InternalErrorType,
+			Code:        internalErr.Code,
+			Msg:         internalErr.Msg,
+			Description: internalErr.Description,
+		}
+	}
+	return http.StatusInternalServerError, ErrorResponse{
+		Type:        InternalErrorType,
+		Code:        http.StatusInternalServerError,
+		Msg:         "Internal Unknown Server Error",
+		Description: err.Error(),
+	}
+}
+
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 		for _, err := range c.Errors {
-			rawErr := err.Unwrap()
 			log.Error(err)
-			var externalErr *ExternalError
-			var internalErr *InternalError
-			if errors.As(rawErr, &externalErr) {
-				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse{
-					Type:        ExternalErrorType,
-					Code:        externalErr.Code,
-					Msg:         externalErr.Msg,
-					Description: externalErr.Description,
-				})
-				return
-			} else if errors.As(rawErr, &internalErr) {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
-					Type:        InternalErrorType,
-					Code:        internalErr.Code,
-					Msg:         internalErr.Msg,
-					Description: internalErr.Description,
-				})
-				return
-			} else {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{
-					Type:        InternalErrorType,
-					Code:        http.StatusInternalServerError,
-					Msg:         "Internal Unknown Server Error",
-					Description: rawErr.Error(),
-				})
-				return
-			}
+			c.AbortWithStatusJSON(NewErrorResponse(err.Unwrap()))
+			return
 		}
 		return
 	}
@@ -27,5 +27,9 @@ func InitRouter() *gin.Engine {
 	apiV1.POST("/excel/compare", v1.Compare)
 	apiV1.POST("/google-sheets/compare", v1.Compare)
 
+	apiV1.POST("/jobs", v1.CreateJob)
+	apiV1.GET("/jobs/:id", v1.GetJob)
+	apiV1.DELETE("/jobs/:id", v1.CancelJob)
+
 	return r
 }
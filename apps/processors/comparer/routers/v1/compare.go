@@ -3,6 +3,7 @@ package v1
 import (
 	"comparer/pkg/app"
 	compareService "comparer/service"
+	"context"
 	"fmt"
 	"net/http"
 
@@ -30,18 +31,25 @@ func Compare(c *gin.Context) {
 		return
 	}
 
+	err = compare(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, nil)
+}
+
+func compare(ctx context.Context, body CompareRequest) error {
 	compareService := compareService.New(compareService.CompareServiceInitParams{
 		DataSourceId: body.DataSourceId,
 		SyncVersion:  *body.SyncVersion,
 		PrevVersion:  *body.PrevVersion,
 	})
 
-	requestContext := c.Request.Context()
-	err = compareService.Run(requestContext)
+	err := compareService.Run(ctx)
 	if err != nil {
-		appG.Error(fmt.Errorf("Error running compare for ds %s: %w", body.DataSourceId, err))
-		return
+		return fmt.Errorf("Error running compare for ds %s: %w", body.DataSourceId, err)
 	}
-
-	appG.Response(http.StatusOK, nil)
+	return nil
 }
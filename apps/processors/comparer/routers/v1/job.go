@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"comparer/pkg/app"
+	"comparer/pkg/e"
+	"comparer/pkg/job"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateJobRequest struct {
+	Type string `form:"type" valid:"Required"`
+	// the body of the matching synchronous endpoint
+	Params json.RawMessage `form:"params"`
+}
+
+// jobFactory validates the params of a job type & returns the work of the job
+type jobFactory func(params []byte) (job.RunFunc, error)
+
+func compareJob(params []byte) (job.RunFunc, error) {
+	var body CompareRequest
+	if err := app.DecodeAndValid(params, &body); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (interface{}, error) {
+		return nil, compare(ctx, body)
+	}, nil
+}
+
+var jobFactories = map[string]jobFactory{
+	"excel/compare":         compareJob,
+	"google-sheets/compare": compareJob,
+}
+
+func CreateJob(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body CreateJobRequest
+	)
+
+	err := app.BindAndValid(c, &body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	factory, ok := jobFactories[body.Type]
+	if !ok {
+		appG.Error(e.NewExternalErrorWithDescription(e.JOB_TYPE_INVALID, "Invalid job type", fmt.Sprintf("Job type %s is not supported", body.Type)))
+		return
+	}
+	run, err := factory(body.Params)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	createdJob, err := job.DefaultManager.Submit(c.Request.Context(), body.Type, run)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusAccepted, createdJob)
+}
+
+func GetJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	foundJob, err := job.DefaultManager.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, foundJob)
+}
+
+func CancelJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	cancelledJob, err := job.DefaultManager.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusAccepted, cancelledJob)
+}
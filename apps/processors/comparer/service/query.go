@@ -4,6 +4,8 @@ import (
 	"comparer/libs/schema"
 	"comparer/pkg/config"
 	"fmt"
+	"path/filepath"
+	"shared/storage"
 	"sort"
 	"strings"
 
@@ -31,8 +33,17 @@ var ClickHouseTypeMap = map[schema.DataType]ClickHouseType{
 	schema.Number:  Decimal128_15,
 	schema.Date:    String,
 	schema.Boolean: Bool,
-	schema.Array:   Array,
+	schema.Array:   String, // JSON array text
 	schema.Unknown: String,
+
+	// same column types as Number & Date, so the values of schemas inferred before them compare equal
+	schema.Integer:  Decimal128_15,
+	schema.Decimal:  Decimal128_15,
+	schema.DateTime: String,
+	schema.Time:     String,
+	schema.Currency: Decimal128_15,
+	schema.Percent:  Decimal128_15,
+	schema.Duration: Decimal128_15,
 }
 
 // ######################################
@@ -43,14 +54,14 @@ type QueryContext struct {
 
 	CompareSchemaResult CompareSchemaResult
 
-	PreviousDataS3Location  string
-	CurrentDataS3Location   string
-	ResultS3Location        string
-	AddedRowsS3Location     string
-	DeletedRowsS3Location   string
-	UpdatedFieldsS3Location string
-	AddedFieldsS3Location   string
-	DeletedFieldsS3Location string
+	PreviousDataLocation  string
+	CurrentDataLocation   string
+	ResultLocation        string
+	AddedRowsLocation     string
+	DeletedRowsLocation   string
+	UpdatedFieldsLocation string
+	AddedFieldsLocation   string
+	DeletedFieldsLocation string
 
 	prevFields  []string // sorted
 	curFields   []string // sorted
@@ -106,9 +117,9 @@ func (c *QueryContext) Setup() {
 func (c *QueryContext) GetFirstVersionCompareQuery() string {
 	currentTableName := "c"
 	query := fmt.Sprintf(
-		`SET s3_truncate_on_insert = 1; %[1]s; %[2]s; %[3]s`,
+		`SET s3_truncate_on_insert = 1, engine_file_truncate_on_insert = 1; %[1]s; %[2]s; %[3]s`,
 		c.GenerateCreateTableQuery(currentTableName, c.CurrentSchema),
-		c.GenerateInsertDataQuery(currentTableName, c.CurrentDataS3Location),
+		c.GenerateInsertDataQuery(currentTableName, c.CurrentDataLocation),
 		c.GenerateFirstVersionAddedRowsTableQuery(currentTableName),
 	)
 	return query
@@ -119,11 +130,11 @@ func (c *QueryContext) GetFullCompareQuery() string {
 	currentTableName := "c"
 	diffTableName := "diff"
 	query := fmt.Sprintf(
-		`SET s3_truncate_on_insert = 1; %[1]s; %[2]s; %[3]s; %[4]s; %[5]s; %[6]s; %[7]s; %[8]s; %[9]s; %[10]s`,
+		`SET s3_truncate_on_insert = 1, engine_file_truncate_on_insert = 1; %[1]s; %[2]s; %[3]s; %[4]s; %[5]s; %[6]s; %[7]s; %[8]s; %[9]s; %[10]s`,
 		c.GenerateCreateTableQuery(previousTableName, c.PreviousSchema),
 		c.GenerateCreateTableQuery(currentTableName, c.CurrentSchema),
-		c.GenerateInsertDataQuery(previousTableName, c.PreviousDataS3Location),
-		c.GenerateInsertDataQuery(currentTableName, c.CurrentDataS3Location),
+		c.GenerateInsertDataQuery(previousTableName, c.PreviousDataLocation),
+		c.GenerateInsertDataQuery(currentTableName, c.CurrentDataLocation),
 		c.GenerateAddedRowsTableQuery(previousTableName, currentTableName),
 		c.GenerateDeletedRowsTableQuery(previousTableName, currentTableName),
 		c.GenerateCreateDiffTableQuery(previousTableName, currentTableName, diffTableName),
@@ -160,19 +171,48 @@ func (c *QueryContext) GenerateCreateTableQuery(tableName string, tableSchema sc
 	)
 	return createTableQuery
 }
-func (c *QueryContext) GenerateInsertDataQuery(tableName, s3Location string) string {
+func (c *QueryContext) GenerateInsertDataQuery(tableName, location string) string {
 	query := fmt.Sprintf(
 		`
             INSERT INTO %[1]s
-            SELECT * FROM s3('%[2]s', '%[3]s', '%[4]s', 'Parquet')
+            SELECT * FROM %[2]s
         `,
 		tableName,
-		s3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(location, "Parquet"),
 	)
 	return query
 }
+
+// tableFunction is the table function of the file at location: s3, or file with the local storage
+func (c *QueryContext) tableFunction(location string, format string) string {
+	if config.AppConfig.StorageBackend == storage.LocalBackend {
+		return fmt.Sprintf(`file('%s', '%s')`, location, format)
+	}
+	return fmt.Sprintf(`s3('%s', '%s', '%s', '%s')`, location, config.AppConfig.S3AccessKey, config.AppConfig.S3SecretKey, format)
+}
+
+// clickhouseFileLocation is the path of a local file read or written by the file table function,
+// relative to the user_files_path of clickhouse when it is configured
+func clickhouseFileLocation(location string) (string, error) {
+	userFilesPath := config.AppConfig.ClickhouseUserFilesPath
+	if userFilesPath == "" {
+		return location, nil
+	}
+	relPath, err := filepath.Rel(userFilesPath, location)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", fmt.Errorf("File %s is not in the clickhouse user files path %s, STORAGE_LOCAL_DIR must be inside CLICKHOUSE_USER_FILES_PATH", location, userFilesPath)
+	}
+	return relPath, nil
+}
+
+// comparedValue returns the expression hashed to compare a field, arrays are compared as sets
+func comparedValue(tableName, field string, tableSchema schema.TableSchema) string {
+	if tableSchema[field].Type == schema.Array {
+		return fmt.Sprintf("arraySort(arrayDistinct(JSONExtractArrayRaw(assumeNotNull(%[1]s.|%[2]s|))))", tableName, field)
+	}
+	return fmt.Sprintf("assumeNotNull(%[1]s.|%[2]s|)", tableName, field)
+}
+
 func (c *QueryContext) GenerateCreateDiffTableQuery(prevTableName, curTableName, resultTableName string) string {
 	selectKeptFields := func(prevTableName, curTableName string, keptFields []string) string {
 		selectKeptFields := lo.Map(keptFields, func(field string, _ int) string {
@@ -185,7 +225,7 @@ func (c *QueryContext) GenerateCreateDiffTableQuery(prevTableName, curTableName,
 						%[1]s.|%[3]s| IS NOT NULL AND %[2]s.|%[3]s| IS NULL,
 						'updateNull',
 						IF (
-							cityHash64(assumeNotNull(%[1]s.|%[3]s|))=cityHash64(assumeNotNull(%[2]s.|%[3]s|)),
+							cityHash64(%[4]s)=cityHash64(%[5]s),
 							'keep',
 							'update'
 						)
@@ -194,6 +234,8 @@ func (c *QueryContext) GenerateCreateDiffTableQuery(prevTableName, curTableName,
 				prevTableName,
 				curTableName,
 				field,
+				comparedValue(prevTableName, field, c.PreviousSchema),
+				comparedValue(curTableName, field, c.CurrentSchema),
 			)
 		})
 		return strings.ReplaceAll(strings.Join(selectKeptFields, ","), "|", "`")
@@ -212,11 +254,11 @@ func (c *QueryContext) GenerateCreateDiffTableQuery(prevTableName, curTableName,
 
 	whereConditionsSubQuery := func(prevTableName, curTableName string, prevFields, curFields []string) string {
 		prevHashFields := lo.Map(prevFields, func(field string, _ int) string {
-			return fmt.Sprintf("assumeNotNull(%[1]s.|%[2]s|)", prevTableName, field)
+			return comparedValue(prevTableName, field, c.PreviousSchema)
 		})
 		prevHashFieldsString := fmt.Sprintf("cityHash64(%[1]s)", strings.Join(prevHashFields, ","))
 		curHashFields := lo.Map(curFields, func(field string, _ int) string {
-			return fmt.Sprintf("assumeNotNull(%[1]s.|%[2]s|)", curTableName, field)
+			return comparedValue(curTableName, field, c.CurrentSchema)
 		})
 		curHashFieldsString := fmt.Sprintf("cityHash64(%[1]s)", strings.Join(curHashFields, ","))
 		return strings.ReplaceAll(fmt.Sprintf("%[1]s != %[2]s", prevHashFieldsString, curHashFieldsString), "|", "`")
@@ -244,18 +286,11 @@ func (c *QueryContext) GenerateFirstVersionAddedRowsTableQuery(curTableName stri
 	query := fmt.Sprintf(
 		`
             INSERT INTO FUNCTION
-                s3(
-                    '%[2]s',
-                    '%[3]s',
-                    '%[4]s',
-                    'JSONCompact'
-                )
+                %[2]s
             SELECT * FROM %[1]s
         `,
 		curTableName,
-		c.AddedRowsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.AddedRowsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 	)
 	return strings.ReplaceAll(query, "|", "`")
@@ -267,19 +302,12 @@ func (c *QueryContext) GenerateAddedRowsTableQuery(prevTableName, curTableName s
 	query := fmt.Sprintf(
 		`
             INSERT INTO FUNCTION
-                s3(
-                    '%[3]s',
-                    '%[4]s',
-                    '%[5]s',
-                    'JSONCompact'
-                )
-            SELECT %[7]s FROM %[1]s RIGHT ANTI JOIN %[2]s ON %[1]s.|%[6]s| = %[2]s.|%[6]s|
+                %[3]s
+            SELECT %[5]s FROM %[1]s RIGHT ANTI JOIN %[2]s ON %[1]s.|%[4]s| = %[2]s.|%[4]s|
         `,
 		prevTableName,
 		curTableName,
-		c.AddedRowsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.AddedRowsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 		selectFields,
 	)
@@ -289,19 +317,12 @@ func (c *QueryContext) GenerateDeletedRowsTableQuery(prevTableName, curTableName
 	query := fmt.Sprintf(
 		`
 			INSERT INTO FUNCTION
-			s3(
-				'%[3]s',
-				'%[4]s',
-				'%[5]s',
-				'JSONCompact'
-			)
-            SELECT %[6]s AS id FROM %[1]s LEFT ANTI JOIN %[2]s ON %[1]s.|%[6]s| = %[2]s.|%[6]s|
+			%[3]s
+            SELECT %[4]s AS id FROM %[1]s LEFT ANTI JOIN %[2]s ON %[1]s.|%[4]s| = %[2]s.|%[4]s|
         `,
 		prevTableName,
 		curTableName,
-		c.DeletedRowsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.DeletedRowsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 	)
 	return strings.ReplaceAll(query, "|", "`")
@@ -317,21 +338,14 @@ func (c *QueryContext) GenerateUpdatedNullFieldsTableQuery(diffTableName string)
 	query := fmt.Sprintf(
 		`
             INSERT INTO FUNCTION
-                s3(
-                    '%[2]s',
-                    '%[3]s',
-                    '%[4]s',
-                    'JSONCompact'
-                )
-            SELECT %[5]s AS id,
-				mapKeys(mapFilter((k,v) -> (v == 'updateNull'), map(%[6]s))) AS updatedNullFields
+                %[2]s
+            SELECT %[3]s AS id,
+				mapKeys(mapFilter((k,v) -> (v == 'updateNull'), map(%[4]s))) AS updatedNullFields
 			FROM %[1]s
 			WHERE length(updatedNullFields) > 0
         `,
 		diffTableName,
-		c.DeletedFieldsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.DeletedFieldsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 		mapBuild,
 	)
@@ -349,24 +363,17 @@ func (c *QueryContext) GenerateUpdatedFieldsTableQuery(curTableName, diffTableNa
 	query := fmt.Sprintf(
 		`
             INSERT INTO FUNCTION
-                s3(
-                    '%[3]s',
-                    '%[4]s',
-                    '%[5]s',
-                    'JSONCompact'
-                )
+                %[3]s
 			SELECT
-				%[6]s AS id, replaceAll(replaceRegexpAll(formatRowNoNewline('JSONEachRow',%[8]s), '"\w+?"\s*:\s*null,?', ''), ',}', '}') AS updatedFields
+				%[4]s AS id, replaceAll(replaceRegexpAll(formatRowNoNewline('JSONEachRow',%[6]s), '"\w+?"\s*:\s*null,?', ''), ',}', '}') AS updatedFields
 			FROM
-				(SELECT %[6]s, %[7]s
-				FROM %[2]s JOIN %[1]s ON %[2]s.|%[6]s| = %[1]s.|%[6]s|
+				(SELECT %[4]s, %[5]s
+				FROM %[2]s JOIN %[1]s ON %[2]s.|%[4]s| = %[1]s.|%[4]s|
 				WHERE length(arrayFilter(x -> x = 'update', array(%[2]s.*))) > 0)
         `,
 		curTableName,
 		diffTableName,
-		c.UpdatedFieldsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.UpdatedFieldsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 		mapBuild,
 		selectKeptFields,
@@ -380,17 +387,10 @@ func (c *QueryContext) GenerateAddedFieldsTableQuery(curTableName, diffTableName
 		return fmt.Sprintf(
 			`
 				INSERT INTO FUNCTION
-					s3(
-						'%[1]s',
-						'%[2]s',
-						'%[3]s',
-						'JSONCompact'
-					)
+					%[1]s
 				SELECT 1 LIMIT 0
 			`,
-			c.AddedFieldsS3Location,
-			config.AppConfig.S3AccessKey,
-			config.AppConfig.S3SecretKey,
+			c.tableFunction(c.AddedFieldsLocation, "JSONCompact"),
 		)
 	}
 
@@ -401,21 +401,14 @@ func (c *QueryContext) GenerateAddedFieldsTableQuery(curTableName, diffTableName
 	query := fmt.Sprintf(
 		`
             INSERT INTO FUNCTION
-                s3(
-                    '%[2]s',
-                    '%[3]s',
-                    '%[4]s',
-                    'JSONCompact'
-                )
+                %[2]s
 			SELECT
-				%[5]s AS id, formatRowNoNewline('JSONEachRow',%[6]s) AS addedFields
+				%[3]s AS id, formatRowNoNewline('JSONEachRow',%[4]s) AS addedFields
 			FROM
 				%[1]s
         `,
 		curTableName,
-		c.AddedFieldsS3Location,
-		config.AppConfig.S3AccessKey,
-		config.AppConfig.S3SecretKey,
+		c.tableFunction(c.AddedFieldsLocation, "JSONCompact"),
 		c.PrimaryColumn,
 		selectAddedFields,
 	)
@@ -3,13 +3,15 @@ package excel
 import (
 	"comparer/libs/schema"
 	"comparer/pkg/config"
-	"comparer/util/s3"
+	"comparer/pkg/job"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"shared/storage"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
 	"github.com/zhenjl/cityhash"
 )
@@ -59,34 +61,36 @@ func New(params CompareServiceInitParams) *CompareService {
 	}
 }
 
-func (s *CompareService) getS3DataFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/data/%s-%d.parquet`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
+func (s *CompareService) getDataFileKey(syncVersion uint) string {
+	return fmt.Sprintf(`data/%s-%d.parquet`, s.dataSourceId, syncVersion)
 }
-func (s *CompareService) getS3SchemaFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/schema/%s-%d.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
+func (s *CompareService) getSchemaFileKey(syncVersion uint) string {
+	return fmt.Sprintf(`schema/%s-%d.json`, s.dataSourceId, syncVersion)
 }
-func (s *CompareService) getS3ResultFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
-}
-func (s *CompareService) getS3ResultAddedRowsFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d-addedRows.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
-}
-func (s *CompareService) getS3ResultDeletedRowsFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d-deletedRows.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
-}
-func (s *CompareService) getS3ResultDeletedFieldsFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d-deletedFields.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
-}
-func (s *CompareService) getS3ResultUpdatedFieldsFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d-updatedFields.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
-}
-func (s *CompareService) getS3ResultAddedFieldsFileLocation(syncVersion uint) string {
-	return fmt.Sprintf(`%s/%s/result/%s-%d-addedFields.json`, config.AppConfig.S3Endpoint, config.AppConfig.S3DiffDataBucket, s.dataSourceId, syncVersion)
+func (s *CompareService) getResultFileKey(syncVersion uint, name string) string {
+	return fmt.Sprintf(`result/%s-%d-%s.json`, s.dataSourceId, syncVersion, name)
 }
 
 func (s *CompareService) CompareData(ctx context.Context) error {
 	log.Info("Running compare for ds " + s.dataSourceId)
 
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return fmt.Errorf("Error when initializing storage: %+v", err)
+	}
+	// locations of the files read & written by clickhouse
+	var locationErr error
+	location := func(key string) string {
+		location, err := storageHandler.Location(key)
+		if err == nil && config.AppConfig.StorageBackend == storage.LocalBackend {
+			location, err = clickhouseFileLocation(location)
+		}
+		if err != nil && locationErr == nil {
+			locationErr = err
+		}
+		return location
+	}
+
 	queryContext := QueryContext{
 		PrimaryColumn:  schema.HashedPrimaryField,
 		PreviousSchema: s.prevSchema,
@@ -94,13 +98,16 @@ func (s *CompareService) CompareData(ctx context.Context) error {
 
 		CompareSchemaResult: s.compareSchemaResult,
 
-		PreviousDataS3Location:  s.getS3DataFileLocation(s.prevVersion),
-		CurrentDataS3Location:   s.getS3DataFileLocation(s.syncVersion),
-		AddedRowsS3Location:     s.getS3ResultAddedRowsFileLocation(s.syncVersion),
-		DeletedRowsS3Location:   s.getS3ResultDeletedRowsFileLocation(s.syncVersion),
-		DeletedFieldsS3Location: s.getS3ResultDeletedFieldsFileLocation(s.syncVersion),
-		UpdatedFieldsS3Location: s.getS3ResultUpdatedFieldsFileLocation(s.syncVersion),
-		AddedFieldsS3Location:   s.getS3ResultAddedFieldsFileLocation(s.syncVersion),
+		PreviousDataLocation:  location(s.getDataFileKey(s.prevVersion)),
+		CurrentDataLocation:   location(s.getDataFileKey(s.syncVersion)),
+		AddedRowsLocation:     location(s.getResultFileKey(s.syncVersion, "addedRows")),
+		DeletedRowsLocation:   location(s.getResultFileKey(s.syncVersion, "deletedRows")),
+		DeletedFieldsLocation: location(s.getResultFileKey(s.syncVersion, "deletedFields")),
+		UpdatedFieldsLocation: location(s.getResultFileKey(s.syncVersion, "updatedFields")),
+		AddedFieldsLocation:   location(s.getResultFileKey(s.syncVersion, "addedFields")),
+	}
+	if locationErr != nil {
+		return locationErr
 	}
 	queryContext.Setup()
 
@@ -121,6 +128,8 @@ func (s *CompareService) CompareData(ctx context.Context) error {
 		fmt.Sprintf(`%s`, query),
 	)
 
+	// relative paths of the file table function are read from the user files path
+	cmd.Dir = config.AppConfig.ClickhouseUserFilesPath
 	outputWriter := s.logger.WriterLevel(log.InfoLevel)
 	errorWriter := s.logger.WriterLevel(log.ErrorLevel)
 	defer outputWriter.Close()
@@ -138,6 +147,25 @@ func (s *CompareService) CompareData(ctx context.Context) error {
 func (s *CompareService) CompareSchema(ctx context.Context) error {
 	log.Info("Running compare schema for ds" + s.dataSourceId)
 
+	compareSchemaResult, err := compareSchemas(s.prevSchema, s.curSchema)
+	if err != nil {
+		return err
+	}
+	s.compareSchemaResult = compareSchemaResult
+
+	schemaDiffResultBytes, err := jsoniter.Marshal(s.compareSchemaResult)
+	if err != nil {
+		return fmt.Errorf("Error when marshalling schema diff result: %+v", err)
+	}
+
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return fmt.Errorf("Error when initializing storage: %+v", err)
+	}
+	return storageHandler.Put(ctx, s.getResultFileKey(s.syncVersion, "schema"), schemaDiffResultBytes, nil)
+}
+
+func compareSchemas(prevSchema, curSchema schema.TableSchema) (CompareSchemaResult, error) {
 	deletedFields := make([]string, 0)
 	addedFields := make(map[string]schema.FieldSchema)
 	updatedFields := make(map[string]schema.FieldSchema)
@@ -148,13 +176,14 @@ func (s *CompareService) CompareSchema(ctx context.Context) error {
 	curFields := make(map[string]schema.FieldSchema)
 
 	fieldsMap := make(map[string]int)
+	legacy := isLegacySchema(prevSchema)
 
 	// 1 = delete, 2 = update, 3 = add
-	for fieldName, field := range s.prevSchema {
+	for fieldName, field := range prevSchema {
 		prevFields[fieldName] = field
 		fieldsMap[fieldName] = 1
 	}
-	for fieldName, field := range s.curSchema {
+	for fieldName, field := range curSchema {
 		curFields[fieldName] = field
 		if _, ok := fieldsMap[fieldName]; !ok {
 			fieldsMap[fieldName] = 3
@@ -170,16 +199,19 @@ func (s *CompareService) CompareSchema(ctx context.Context) error {
 			// field is added
 			addedFields[fieldName] = curFields[fieldName]
 		} else {
-			prevField := prevFields[fieldName]
 			curField := curFields[fieldName]
-			// field may be updated, compare field
-			marshaledPrevField, err := jsoniter.Marshal(prevField)
+			prevField := prevFields[fieldName]
+			if legacy {
+				prevField = upgradeLegacyField(prevField, curField)
+			}
+			// field may be updated, compare field without the inference statistics changing every sync
+			marshaledPrevField, err := jsoniter.Marshal(fieldDefinition(prevField))
 			if err != nil {
-				return fmt.Errorf("Error when marshalling prev field: %+v", err)
+				return CompareSchemaResult{}, fmt.Errorf("Error when marshalling prev field: %+v", err)
 			}
-			marshaledCurField, err := jsoniter.Marshal(curField)
+			marshaledCurField, err := jsoniter.Marshal(fieldDefinition(curField))
 			if err != nil {
-				return fmt.Errorf("Error when marshalling cur field: %+v", err)
+				return CompareSchemaResult{}, fmt.Errorf("Error when marshalling cur field: %+v", err)
 			}
 			if cityhash.CityHash64(marshaledPrevField, uint32(len(marshaledPrevField))) != cityhash.CityHash64(marshaledCurField, uint32(len(marshaledCurField))) {
 				updatedFields[fieldName] = curField
@@ -193,50 +225,59 @@ func (s *CompareService) CompareSchema(ctx context.Context) error {
 
 	}
 
-	s.compareSchemaResult = CompareSchemaResult{
+	return CompareSchemaResult{
 		DeletedFields:     deletedFields,
 		AddedFields:       addedFields,
 		UpdatedFields:     updatedFields,
 		UpdatedTypeFields: updatedTypeFields,
 		KeptFields:        keptFields,
-	}
+	}, nil
+}
 
-	schemaDiffResultBytes, err := jsoniter.Marshal(s.compareSchemaResult)
-	if err != nil {
-		return fmt.Errorf("Error when marshalling schema diff result: %+v", err)
+// fieldDefinition drops the inference statistics of the field
+func fieldDefinition(field schema.FieldSchema) schema.FieldSchema {
+	field.Confidence = 0
+	field.SampledCount = 0
+	return field
+}
+
+// types of the schemas inferred before the variants, e.g. a Number field is now inferred as an Integer or a Decimal
+var legacyTypeVariants = map[schema.DataType][]schema.DataType{
+	schema.Number:  {schema.Integer, schema.Decimal, schema.Currency, schema.Percent, schema.Duration},
+	schema.Date:    {schema.Date, schema.DateTime, schema.Time},
+	schema.Boolean: {schema.Boolean},
+}
+
+// isLegacySchema tells if the schema was inferred before the variants, its fields have no inference statistics
+func isLegacySchema(tableSchema schema.TableSchema) bool {
+	for _, field := range tableSchema {
+		if field.SampledCount > 0 {
+			return false
+		}
 	}
+	return true
+}
 
-	handler, err := s3.NewHandlerWithConfig(&s3.S3HandlerConfig{
-		Endpoint:  config.AppConfig.S3Endpoint,
-		Region:    config.AppConfig.S3Region,
-		Bucket:    config.AppConfig.S3DiffDataBucket,
-		AccessKey: config.AppConfig.S3AccessKey,
-		SecretKey: config.AppConfig.S3SecretKey,
-	})
-	if err != nil {
-		return fmt.Errorf("Error when initializing s3 handler: %+v", err)
+// upgradeLegacyField gives a field of a legacy schema the variant of the current field,
+// so that the fields are not updated on the first sync after the upgrade
+func upgradeLegacyField(prevField, curField schema.FieldSchema) schema.FieldSchema {
+	if !lo.Contains(legacyTypeVariants[prevField.Type], curField.Type) {
+		return prevField
 	}
-	schemaDiffResultFileKey := fmt.Sprintf("result/%s-%d-schema.json", s.dataSourceId, s.syncVersion)
-	return handler.UploadFileWithBytes(schemaDiffResultFileKey, schemaDiffResultBytes)
+	prevField.Type = curField.Type
+	prevField.Format = curField.Format
+	prevField.Unit = curField.Unit
+	return prevField
 }
 
 func (s *CompareService) GetSchema(ctx context.Context) error {
 	log.Info("Getting schema for ds " + s.dataSourceId)
-	appConfig := config.AppConfig
-	s3Config := s3.S3HandlerConfig{
-		Endpoint:  appConfig.S3Endpoint,
-		Region:    appConfig.S3Region,
-		Bucket:    appConfig.S3DiffDataBucket,
-		AccessKey: appConfig.S3AccessKey,
-		SecretKey: appConfig.S3SecretKey,
-	}
-	handler, err := s3.NewHandlerWithConfig(&s3Config)
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
 	if err != nil {
-		log.Error("Error when initializing s3 handler: ", err)
+		log.Error("Error when initializing storage: ", err)
 		return err
 	}
-	schemaFileKey := fmt.Sprintf("schema/%s-%d.json", s.dataSourceId, s.syncVersion)
-	schemaFile, err := handler.ReadFileByte(schemaFileKey)
+	schemaFile, err := storageHandler.Get(ctx, s.getSchemaFileKey(s.syncVersion))
 	if err != nil {
 		log.Error("Error when reading schema file: ", err)
 		return err
@@ -248,8 +289,7 @@ func (s *CompareService) GetSchema(ctx context.Context) error {
 	}
 	// get previous schema
 	if s.prevVersion != 0 {
-		prevSchemaFileKey := fmt.Sprintf("schema/%s-%d.json", s.dataSourceId, s.prevVersion)
-		schemaFile, err := handler.ReadFileByte(prevSchemaFileKey)
+		schemaFile, err := storageHandler.Get(ctx, s.getSchemaFileKey(s.prevVersion))
 		if err != nil {
 			log.Error("Error when reading schema file: ", err)
 			return err
@@ -264,6 +304,7 @@ func (s *CompareService) GetSchema(ctx context.Context) error {
 }
 
 func (s *CompareService) Run(ctx context.Context) error {
+	job.ReportStage(ctx, "schema")
 	err := s.GetSchema(ctx)
 	if err != nil {
 		return err
@@ -276,6 +317,7 @@ func (s *CompareService) Run(ctx context.Context) error {
 		}
 	}
 
+	job.ReportStage(ctx, "data")
 	err = s.CompareData(ctx)
 	if err != nil {
 		return err
@@ -0,0 +1,57 @@
+package excel
+
+import (
+	"comparer/libs/schema"
+	"testing"
+)
+
+func TestCompareSchemas(t *testing.T) {
+	field := func(dataType schema.DataType, format, unit string, sampledCount int) schema.FieldSchema {
+		return schema.FieldSchema{Name: "field", Type: dataType, OriginalType: "Number", Nullable: true, Format: format, Unit: unit, SampledCount: sampledCount}
+	}
+	tests := []struct {
+		name        string
+		prev        schema.FieldSchema
+		cur         schema.FieldSchema
+		wantUpdated bool
+		wantType    bool // the type is reported as updated
+	}{
+		{name: "legacy number to integer", prev: field(schema.Number, "", "", 0), cur: field(schema.Integer, "", "", 10)},
+		{name: "legacy number to decimal", prev: field(schema.Number, "", "", 0), cur: field(schema.Decimal, "", "", 10)},
+		{name: "legacy number to currency", prev: field(schema.Number, "", "", 0), cur: field(schema.Currency, "", "USD", 10)},
+		{name: "legacy number to percent", prev: field(schema.Number, "", "", 0), cur: field(schema.Percent, "", "ratio", 10)},
+		{name: "legacy date to date with format", prev: field(schema.Date, "", "", 0), cur: field(schema.Date, "%d/%m/%Y", "", 10)},
+		{name: "legacy date to datetime", prev: field(schema.Date, "", "", 0), cur: field(schema.DateTime, "", "", 10)},
+		{name: "legacy date to time", prev: field(schema.Date, "", "", 0), cur: field(schema.Time, "", "", 10)},
+		{name: "legacy boolean to boolean variant", prev: field(schema.Boolean, "", "", 0), cur: field(schema.Boolean, "yes/no", "", 10)},
+		{name: "legacy number to string", prev: field(schema.Number, "", "", 0), cur: field(schema.String, "", "", 10), wantUpdated: true, wantType: true},
+		{name: "legacy date to integer", prev: field(schema.Date, "", "", 0), cur: field(schema.Integer, "", "", 10), wantUpdated: true, wantType: true},
+		{name: "date to datetime", prev: field(schema.Date, "", "", 10), cur: field(schema.DateTime, "", "", 10), wantUpdated: true, wantType: true},
+		{name: "currency unit", prev: field(schema.Currency, "", "USD", 10), cur: field(schema.Currency, "", "EUR", 10), wantUpdated: true},
+		{name: "inference statistics", prev: field(schema.Integer, "", "", 10), cur: field(schema.Integer, "", "", 20)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary := schema.FieldSchema{Name: schema.PrimaryFieldName, Type: schema.String, OriginalType: "String", Primary: true}
+			prevSchema := schema.TableSchema{schema.HashedPrimaryField: primary, "f_field": test.prev}
+			curSchema := schema.TableSchema{schema.HashedPrimaryField: primary, "f_field": test.cur}
+
+			result, err := compareSchemas(prevSchema, curSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := result.UpdatedFields["f_field"]; ok != test.wantUpdated {
+				t.Errorf("got updated %v, want %v", ok, test.wantUpdated)
+			}
+			if _, ok := result.KeptFields["f_field"]; ok == test.wantUpdated {
+				t.Errorf("got kept %v, want %v", ok, !test.wantUpdated)
+			}
+			if _, ok := result.UpdatedTypeFields["f_field"]; ok != test.wantType {
+				t.Errorf("got type updated %v, want %v", ok, test.wantType)
+			}
+			if len(result.AddedFields) != 0 || len(result.DeletedFields) != 0 {
+				t.Errorf("got added %v & deleted %v, want none", result.AddedFields, result.DeletedFields)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ import (
 )
 
 const (
-	S3_DEFAULT_ACL = "private"
+	S3_DEFAULT_ACL        = "private"
+	S3_UPLOAD_PART_SIZE   = 16 * 1024 * 1024
+	S3_UPLOAD_CONCURRENCY = 4
 )
 
 type S3HandlerConfig struct {
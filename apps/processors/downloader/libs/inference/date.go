@@ -0,0 +1,248 @@
+package inference
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// DateOrder is the order of the day, month & year of numeric dates, e.g. 03/04/2024
+type DateOrder string
+
+const (
+	DMY DateOrder = "DMY"
+	MDY DateOrder = "MDY"
+	YMD DateOrder = "YMD"
+
+	// day first when the values don't tell, the order the sheets were read with before
+	DefaultDateOrder = DMY
+)
+
+func ParseDateOrder(order string) (DateOrder, error) {
+	switch dateOrder := DateOrder(strings.ToUpper(strings.TrimSpace(order))); dateOrder {
+	case "":
+		return "", nil
+	case DMY, MDY, YMD:
+		return dateOrder, nil
+	default:
+		return "", fmt.Errorf("unknown date order %s, expected DMY, MDY or YMD", order)
+	}
+}
+
+var numericDateRegex = regexp.MustCompile(`^(\d{1,4})([/.-])(\d{1,2})([/.-])(\d{1,4})(?:[ T](\d{1,2}):(\d{2})(?::(\d{2})(?:\.(\d{1,9}))?)?\s*([AaPp][Mm])?)?$`)
+
+// NumericDate is a date written with numbers only, its day & month can only be told apart by a DateOrder
+type NumericDate struct {
+	parts     [3]int
+	widths    [3]int
+	separator string
+
+	hasTime, hasSeconds bool
+	hour, minute        int
+	second, nanosecond  int
+	meridiem            string
+}
+
+func ParseNumericDate(text string) (NumericDate, bool) {
+	match := numericDateRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil || match[2] != match[4] {
+		return NumericDate{}, false
+	}
+	var date NumericDate
+	for idx, part := range []string{match[1], match[3], match[5]} {
+		date.parts[idx], _ = strconv.Atoi(part)
+		date.widths[idx] = len(part)
+	}
+	date.separator = match[2]
+	if match[6] != "" {
+		date.hasTime = true
+		date.hour, _ = strconv.Atoi(match[6])
+		date.minute, _ = strconv.Atoi(match[7])
+		if match[8] != "" {
+			date.hasSeconds = true
+			date.second, _ = strconv.Atoi(match[8])
+		}
+		if match[9] != "" {
+			date.nanosecond, _ = strconv.Atoi((match[9] + "00000000")[:9])
+		}
+		date.meridiem = strings.ToUpper(match[10])
+	}
+	return date, true
+}
+
+func (d NumericDate) HasTime() bool {
+	return d.hasTime
+}
+
+// fields returns the year, month & day of the date read in the order
+func (d NumericDate) fields(order DateOrder) (year, month, day, yearWidth int) {
+	switch order {
+	case YMD:
+		return d.parts[0], d.parts[1], d.parts[2], d.widths[0]
+	case MDY:
+		return d.parts[2], d.parts[0], d.parts[1], d.widths[2]
+	default:
+		return d.parts[2], d.parts[1], d.parts[0], d.widths[2]
+	}
+}
+
+// Time returns the date read in the order, false when it is not a valid date in that order
+func (d NumericDate) Time(order DateOrder, location *time.Location) (time.Time, bool) {
+	year, month, day, yearWidth := d.fields(order)
+	switch yearWidth {
+	case 4:
+	case 2:
+		// same pivot as %y
+		if year < 69 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	default:
+		return time.Time{}, false
+	}
+	if (order != YMD && d.widths[0] > 2) || (order == YMD && d.widths[2] > 2) {
+		return time.Time{}, false
+	}
+
+	hour := d.hour
+	switch d.meridiem {
+	case "AM", "PM":
+		if hour < 1 || hour > 12 {
+			return time.Time{}, false
+		}
+		hour %= 12
+		if d.meridiem == "PM" {
+			hour += 12
+		}
+	}
+	if hour > 23 || d.minute > 59 || d.second > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, hour, d.minute, d.second, d.nanosecond, location)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Orders returns the orders the date is valid in
+func (d NumericDate) Orders() []DateOrder {
+	orders := make([]DateOrder, 0, 3)
+	for _, order := range []DateOrder{DMY, MDY, YMD} {
+		if _, ok := d.Time(order, time.UTC); ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// Format returns the strftime format the date is read with in the order
+func (d NumericDate) Format(order DateOrder) string {
+	yearFormat := "%Y"
+	if _, _, _, yearWidth := d.fields(order); yearWidth == 2 {
+		yearFormat = "%y"
+	}
+	var parts []string
+	switch order {
+	case YMD:
+		parts = []string{yearFormat, "%m", "%d"}
+	case MDY:
+		parts = []string{"%m", "%d", yearFormat}
+	default:
+		parts = []string{"%d", "%m", yearFormat}
+	}
+	format := strings.Join(parts, d.separator)
+	if d.hasTime {
+		hourFormat := "%H"
+		if d.meridiem != "" {
+			hourFormat = "%I"
+		}
+		format += " " + hourFormat + ":%M"
+		if d.hasSeconds {
+			format += ":%S"
+		}
+		if d.meridiem != "" {
+			format += " %p"
+		}
+	}
+	return format
+}
+
+// DetectDateOrder returns the order all the dates are valid in. When several fit, the preferred order is used,
+// then the default one. A preferred order is only overridden by year first dates, e.g. ISO dates.
+func DetectDateOrder(dates []NumericDate, preferred DateOrder) (DateOrder, bool) {
+	if len(dates) == 0 {
+		return "", false
+	}
+	counts := make(map[DateOrder]int)
+	for _, date := range dates {
+		for _, order := range date.Orders() {
+			counts[order]++
+		}
+	}
+	candidates := lo.Filter([]DateOrder{DMY, MDY, YMD}, func(order DateOrder, _ int) bool {
+		return counts[order] == len(dates)
+	})
+	switch {
+	case len(candidates) == 0:
+		return "", false
+	case preferred != "" && lo.Contains(candidates, preferred):
+		return preferred, true
+	case preferred != "":
+		return YMD, len(candidates) == 1 && candidates[0] == YMD
+	case lo.Contains(candidates, DefaultDateOrder):
+		return DefaultDateOrder, true
+	default:
+		return candidates[0], true
+	}
+}
+
+// #########################################################################################################
+
+var strftimeDirectives = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'j': "002",
+	'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+	'H': "15", 'I': "03", 'M': "04", 'S': "05", 'f': "000000", 'p': "PM",
+	'z': "-0700", 'Z': "MST",
+	'F': "2006-01-02", 'T': "15:04:05", 'D': "01/02/06", 'R': "15:04",
+	'%': "%",
+}
+
+// StrftimeLayout converts a strftime format, e.g. %d/%m/%Y, to a time layout.
+// The second result is true when the format has a time of day.
+func StrftimeLayout(format string) (string, bool, error) {
+	var layout strings.Builder
+	hasTime := false
+	for idx := 0; idx < len(format); idx++ {
+		if format[idx] != '%' {
+			layout.WriteByte(format[idx])
+			continue
+		}
+		idx++
+		if idx == len(format) {
+			return "", false, fmt.Errorf("format %s ends with %%", format)
+		}
+		directive := format[idx]
+		// %-d & %-m are not padded
+		if directive == '-' && idx+1 < len(format) && strings.IndexByte("dmHIMS", format[idx+1]) != -1 {
+			idx++
+			directive = format[idx]
+			layout.WriteString(strings.TrimPrefix(strftimeDirectives[directive], "0"))
+		} else {
+			value, ok := strftimeDirectives[directive]
+			if !ok {
+				return "", false, fmt.Errorf("unknown directive %%%c in format %s", directive, format)
+			}
+			layout.WriteString(value)
+		}
+		if strings.IndexByte("HIMSfpTR", directive) != -1 {
+			hasTime = true
+		}
+	}
+	return layout.String(), hasTime, nil
+}
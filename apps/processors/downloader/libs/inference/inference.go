@@ -0,0 +1,446 @@
+package inference
+
+import (
+	exceltype "downloader/libs/datatype/excel"
+	"downloader/libs/schema"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/samber/lo"
+)
+
+const DefaultEnumThreshold = 5
+
+// Kind of a single value
+type Kind int
+
+const (
+	KindEmpty Kind = iota
+	KindString
+	KindInteger
+	KindDecimal
+	KindDate
+	KindDateTime
+	KindTime
+	KindBoolean
+	KindCurrency
+	KindPercent
+	KindDuration
+	KindArray // JSON array, e.g. a multi-select value
+)
+
+// Boolean variants, reported as the format of boolean fields
+const (
+	BooleanTrueFalse = "true/false"
+	BooleanYesNo     = "yes/no"
+	BooleanYN        = "y/n"
+	BooleanOnOff     = "on/off"
+	BooleanCheckbox  = "checkbox" // boolean cells of the sheet, e.g. checkboxes
+)
+
+var textBooleans = map[string]struct {
+	format string
+	value  bool
+}{
+	"true":  {BooleanTrueFalse, true},
+	"false": {BooleanTrueFalse, false},
+	"yes":   {BooleanYesNo, true},
+	"no":    {BooleanYesNo, false},
+	"y":     {BooleanYN, true},
+	"n":     {BooleanYN, false},
+	"on":    {BooleanOnOff, true},
+	"off":   {BooleanOnOff, false},
+}
+
+var (
+	dateLayouts     = []string{"2006-01-02"}
+	dateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+	timeLayouts     = []string{"15:04:05.999999999", "15:04"}
+)
+
+type Value struct {
+	Kind          Kind
+	Text          string // value written to the snapshot
+	BooleanFormat string
+	BooleanValue  bool
+	Unit          string // currency code or unit of a currency, percent or duration
+	Items         []Value
+}
+
+// ClassifyString detects the kind of a text value, e.g. a CSV field
+func ClassifyString(text string) Value {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return Value{Kind: KindEmpty, Text: text}
+	}
+	// codes written with leading zeros, e.g. zip codes & phone numbers, would lose them as numbers
+	if isDecimal(trimmed) && hasLeadingZero(trimmed) {
+		return Value{Kind: KindString, Text: text}
+	}
+	if _, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return Value{Kind: KindInteger, Text: text}
+	}
+	if items, ok := classifyJsonArray(trimmed); ok {
+		return Value{Kind: KindArray, Text: text, Items: items}
+	}
+	if isDecimal(trimmed) {
+		return Value{Kind: KindDecimal, Text: text}
+	}
+	if boolean, ok := textBooleans[strings.ToLower(trimmed)]; ok {
+		return Value{Kind: KindBoolean, Text: text, BooleanFormat: boolean.format, BooleanValue: boolean.value}
+	}
+	switch {
+	case matchLayout(trimmed, dateLayouts):
+		return Value{Kind: KindDate, Text: text}
+	case matchLayout(trimmed, dateTimeLayouts):
+		return Value{Kind: KindDateTime, Text: text}
+	case matchLayout(trimmed, timeLayouts):
+		return Value{Kind: KindTime, Text: text}
+	}
+	return Value{Kind: KindString, Text: text}
+}
+
+// ClassifyNumber detects whether a number is an integer or a decimal
+func ClassifyNumber(text string) Value {
+	if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return Value{Kind: KindInteger, Text: text}
+	}
+	if isDecimal(text) {
+		return Value{Kind: KindDecimal, Text: text}
+	}
+	return Value{Kind: KindString, Text: text}
+}
+
+// ClassifySerialDate detects the kind of a date cell from its serial number:
+// a whole day is a date, less than a day a time of day
+func ClassifySerialDate(text string, serialNumber float64) Value {
+	switch {
+	case serialNumber < 1:
+		return Value{Kind: KindTime, Text: text}
+	case serialNumber == float64(int64(serialNumber)):
+		return Value{Kind: KindDate, Text: text}
+	default:
+		return Value{Kind: KindDateTime, Text: text}
+	}
+}
+
+// classifyJsonArray detects the items of a JSON array of scalars
+func classifyJsonArray(text string) ([]Value, bool) {
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return nil, false
+	}
+	var elements []interface{}
+	if err := jsoniter.UnmarshalFromString(text, &elements); err != nil {
+		return nil, false
+	}
+	items := make([]Value, 0, len(elements))
+	for _, element := range elements {
+		switch v := element.(type) {
+		case string:
+			items = append(items, ClassifyString(v))
+		case float64:
+			items = append(items, ClassifyNumber(strconv.FormatFloat(v, 'f', -1, 64)))
+		case bool:
+			items = append(items, Value{Kind: KindBoolean, Text: strconv.FormatBool(v), BooleanFormat: BooleanTrueFalse, BooleanValue: v})
+		case nil:
+		default:
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+// hasLeadingZero tells if the integer part of a number has a leading zero, e.g. 007 or -01.5, but not 0 or 0.5
+func hasLeadingZero(text string) bool {
+	text = strings.TrimLeft(text, "+-")
+	return len(text) > 1 && text[0] == '0' && text[1] >= '0' && text[1] <= '9'
+}
+
+func isDecimal(text string) bool {
+	// ParseFloat accepts Inf & NaN, they are not decimals
+	for _, r := range text {
+		if !strings.ContainsRune("0123456789+-.eE", r) {
+			return false
+		}
+	}
+	_, err := strconv.ParseFloat(text, 64)
+	return err == nil
+}
+
+func matchLayout(text string, layouts []string) bool {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// #########################################################################################################
+
+type Options struct {
+	// non empty values sampled per column, 0 to sample every value
+	SampleSize int
+	// share of the sampled values that must fit a type for the field to get it, 1 when 0.
+	// The values that don't fit are still written to the snapshot, keep it at 1 unless they are cleaned later.
+	MinConfidence float64
+	EnumThreshold int
+}
+
+func (o Options) withDefaults() Options {
+	if o.MinConfidence == 0 {
+		o.MinConfidence = 1
+	}
+	if o.EnumThreshold == 0 {
+		o.EnumThreshold = DefaultEnumThreshold
+	}
+	return o
+}
+
+type Result struct {
+	Type         schema.DataType
+	OriginalType exceltype.ExcelDataType
+	Format       string
+	Unit         string
+	ItemType     schema.DataType // type of the items of an Array
+	// share of the sampled values supporting the type, for String the share of values fitting no other type
+	Confidence   float64
+	SampledCount int
+	Enum         []interface{}
+}
+
+func (r Result) FieldSchema(name string) schema.FieldSchema {
+	return schema.FieldSchema{
+		Name:         name,
+		Type:         r.Type,
+		OriginalType: string(r.OriginalType),
+		Nullable:     true,
+		Enum:         r.Enum,
+		Format:       r.Format,
+		Unit:         r.Unit,
+		ItemType:     r.ItemType,
+		Confidence:   r.Confidence,
+		SampledCount: r.SampledCount,
+	}
+}
+
+func PrimaryFieldSchema() schema.FieldSchema {
+	return schema.FieldSchema{
+		Name:         schema.PrimaryFieldName,
+		Type:         schema.String,
+		OriginalType: string(exceltype.String),
+		Nullable:     false,
+		Primary:      true,
+	}
+}
+
+// CellMetaFieldSchema is the schema of the column holding the captured hyperlinks, notes & formulas of a row
+func CellMetaFieldSchema() schema.FieldSchema {
+	return rowMetadataFieldSchema(schema.OriginalCellMetaFieldName)
+}
+
+// CellErrorsFieldSchema is the schema of the column holding the spreadsheet errors of a row
+func CellErrorsFieldSchema() schema.FieldSchema {
+	return rowMetadataFieldSchema(schema.OriginalCellErrorsFieldName)
+}
+
+func rowMetadataFieldSchema(name string) schema.FieldSchema {
+	return schema.FieldSchema{
+		Name:         name,
+		Type:         schema.String,
+		OriginalType: string(exceltype.String),
+		Nullable:     true,
+		Readonly:     true,
+	}
+}
+
+// ColumnInferrer infers the type of a column from its values
+type ColumnInferrer struct {
+	options Options
+
+	sampled        int
+	kindCounts     map[Kind]int
+	booleanFormats map[string]int
+	booleanValues  map[bool]bool
+	units          map[Kind]map[string]int
+	items          *ColumnInferrer
+	distinct       []string
+	seen           map[string]bool
+}
+
+func NewColumnInferrer(options Options) *ColumnInferrer {
+	return &ColumnInferrer{
+		options:        options.withDefaults(),
+		kindCounts:     make(map[Kind]int),
+		booleanFormats: make(map[string]int),
+		booleanValues:  make(map[bool]bool),
+		units:          make(map[Kind]map[string]int),
+		seen:           make(map[string]bool),
+	}
+}
+
+// Done returns true when the sample is full
+func (c *ColumnInferrer) Done() bool {
+	return c.options.SampleSize > 0 && c.sampled >= c.options.SampleSize
+}
+
+func (c *ColumnInferrer) Add(value Value) {
+	if value.Kind == KindEmpty || c.Done() {
+		return
+	}
+	c.sampled++
+	c.kindCounts[value.Kind]++
+	if value.Kind == KindBoolean {
+		c.booleanFormats[value.BooleanFormat]++
+		c.booleanValues[value.BooleanValue] = true
+	}
+	if value.Kind == KindCurrency || value.Kind == KindPercent || value.Kind == KindDuration {
+		if c.units[value.Kind] == nil {
+			c.units[value.Kind] = make(map[string]int)
+		}
+		c.units[value.Kind][value.Unit]++
+	}
+	if value.Kind == KindArray {
+		if c.items == nil {
+			c.items = NewColumnInferrer(Options{EnumThreshold: c.options.EnumThreshold})
+		}
+		for _, item := range value.Items {
+			c.items.Add(item)
+		}
+	}
+	if !c.seen[value.Text] && len(c.distinct) <= c.options.EnumThreshold {
+		c.seen[value.Text] = true
+		c.distinct = append(c.distinct, value.Text)
+	}
+}
+
+type candidate struct {
+	dataType     schema.DataType
+	originalType exceltype.ExcelDataType
+	format       string
+	unit         string
+	count        int
+}
+
+func (c *ColumnInferrer) Result() Result {
+	result := Result{
+		Type:         schema.String,
+		OriginalType: exceltype.String,
+		SampledCount: c.sampled,
+	}
+	if c.sampled == 0 {
+		return result
+	}
+
+	// narrower types first, the first candidate wins a tie
+	booleanFormat, booleanCount := c.booleanFormat()
+	currency, currencyCount := c.unit(KindCurrency)
+	percentUnit, percentCount := c.unit(KindPercent)
+	durationUnit, durationCount := c.unit(KindDuration)
+	numberCount := c.kindCounts[KindInteger] + c.kindCounts[KindDecimal] +
+		c.kindCounts[KindCurrency] + c.kindCounts[KindPercent] + c.kindCounts[KindDuration]
+	candidates := []candidate{
+		{schema.Boolean, exceltype.Logical, booleanFormat, "", booleanCount},
+		{schema.Currency, exceltype.Number, "", currency, currencyCount},
+		{schema.Percent, exceltype.Number, "", percentUnit, percentCount},
+		{schema.Duration, exceltype.Number, "", durationUnit, durationCount},
+		{schema.Integer, exceltype.Number, "", "", c.kindCounts[KindInteger]},
+		{schema.Decimal, exceltype.Number, "", "", numberCount},
+		{schema.Date, exceltype.Number, "", "", c.kindCounts[KindDate]},
+		{schema.DateTime, exceltype.Number, "", "", c.kindCounts[KindDate] + c.kindCounts[KindDateTime]},
+		{schema.Time, exceltype.Number, "", "", c.kindCounts[KindTime]},
+		{schema.Array, exceltype.String, "", "", c.kindCounts[KindArray]},
+	}
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.count > best.count {
+			best = candidate
+		}
+	}
+
+	confidence := float64(best.count) / float64(c.sampled)
+	if best.count == 0 || confidence < c.options.MinConfidence {
+		result.Confidence = float64(c.kindCounts[KindString]) / float64(c.sampled)
+		result.Enum = stringEnum(c.distinct, c.options.EnumThreshold)
+		return result
+	}
+
+	result.Type = best.dataType
+	result.OriginalType = best.originalType
+	result.Format = best.format
+	result.Unit = best.unit
+	result.Confidence = confidence
+	switch best.dataType {
+	case schema.Integer, schema.Decimal, schema.Currency, schema.Percent, schema.Duration:
+		result.Enum = numberEnum(c.distinct, c.options.EnumThreshold)
+	case schema.Boolean:
+	case schema.Array:
+		result.ItemType = schema.String
+		if c.items != nil && c.items.sampled > 0 {
+			result.ItemType = c.items.Result().Type
+		}
+	default:
+		result.Enum = stringEnum(c.distinct, c.options.EnumThreshold)
+	}
+	return result
+}
+
+// booleanFormat returns the most used boolean variant & its count,
+// a text variant must have both values to not mistake a text column holding "yes" for a boolean
+func (c *ColumnInferrer) booleanFormat() (string, int) {
+	format, count := "", 0
+	for f, n := range c.booleanFormats {
+		if n > count || (n == count && f < format) {
+			format, count = f, n
+		}
+	}
+	if format != BooleanCheckbox && len(c.booleanValues) < 2 {
+		return "", 0
+	}
+	return format, count
+}
+
+// unit returns the most used unit of the kind & its count, values of a unit in the minority don't support the kind
+func (c *ColumnInferrer) unit(kind Kind) (string, int) {
+	unit, count := "", 0
+	for u, n := range c.units[kind] {
+		if n > count || (n == count && u < unit) {
+			unit, count = u, n
+		}
+	}
+	return unit, count
+}
+
+func stringEnum(values []string, threshold int) []interface{} {
+	if len(values) == 0 || len(values) > threshold {
+		return nil
+	}
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return lo.Map(sorted, func(value string, _ int) interface{} { return value })
+}
+
+func numberEnum(values []string, threshold int) []interface{} {
+	if len(values) == 0 || len(values) > threshold {
+		return nil
+	}
+	numbers := make([]float64, 0, len(values))
+	for _, value := range values {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	numbers = lo.Uniq(numbers)
+	sort.Float64s(numbers)
+	return lo.Map(numbers, func(number float64, _ int) interface{} {
+		if number == float64(int64(number)) {
+			return int64(number)
+		}
+		return number
+	})
+}
@@ -0,0 +1,63 @@
+package inference
+
+import (
+	"downloader/libs/schema"
+	"testing"
+)
+
+func TestClassifyString(t *testing.T) {
+	tests := []struct {
+		text string
+		want Kind
+	}{
+		{"", KindEmpty},
+		{"  ", KindEmpty},
+		{"42", KindInteger},
+		{"-42", KindInteger},
+		{"0", KindInteger},
+		{"007", KindString},
+		{"01234", KindString},
+		{"-01", KindString},
+		{"0042.5", KindString},
+		{"0.5", KindDecimal},
+		{"-0.5", KindDecimal},
+		{"1.5e3", KindDecimal},
+		{"yes", KindBoolean},
+		{"TRUE", KindBoolean},
+		{"2023-03-15", KindDate},
+		{"2023-03-15 10:30", KindDateTime},
+		{"09:30", KindTime},
+		{`["a", "b"]`, KindArray},
+		{"abc", KindString},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if got := ClassifyString(test.text).Kind; got != test.want {
+				t.Errorf("ClassifyString(%q) = %d, want %d", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestColumnInferrerLeadingZeros(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   schema.DataType
+	}{
+		{name: "integers", values: []string{"1", "20", "300"}, want: schema.Integer},
+		{name: "zip codes", values: []string{"01234", "75001", "00501"}, want: schema.String},
+		{name: "ids", values: []string{"007", "008", "009"}, want: schema.String},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inferrer := NewColumnInferrer(Options{})
+			for _, value := range test.values {
+				inferrer.Add(ClassifyString(value))
+			}
+			if got := inferrer.Result().Type; got != test.want {
+				t.Errorf("got type %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package inference
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+// Locale is the way a spreadsheet locale writes numbers & booleans as text
+type Locale struct {
+	Name             string
+	DecimalSeparator string
+	GroupSeparators  []string
+	True, False      string // words of the TRUE & FALSE functions
+}
+
+var (
+	dotGroup          = []string{"."}
+	spaceGroup        = []string{" ", nbsp, narrowNbsp}
+	englishNumbers    = Locale{DecimalSeparator: ".", GroupSeparators: []string{","}}
+	spaceGroupLocales = map[string]bool{"fr": true, "ru": true, "pl": true, "cs": true, "sk": true, "sv": true, "fi": true, "nb": true, "no": true, "uk": true, "hu": true}
+)
+
+// language locales, the region only changes the separators of a few of them
+var locales = map[string]Locale{
+	"en": {True: "TRUE", False: "FALSE"},
+	"de": {True: "WAHR", False: "FALSCH"},
+	"fr": {True: "VRAI", False: "FAUX"},
+	"es": {True: "VERDADERO", False: "FALSO"},
+	"it": {True: "VERO", False: "FALSO"},
+	"pt": {True: "VERDADEIRO", False: "FALSO"},
+	"nl": {True: "WAAR", False: "ONWAAR"},
+	"sv": {True: "SANT", False: "FALSKT"},
+	"da": {True: "SAND", False: "FALSK"},
+	"nb": {True: "SANN", False: "USANN"},
+	"no": {True: "SANN", False: "USANN"},
+	"fi": {True: "TOSI", False: "EPÄTOSI"},
+	"pl": {True: "PRAWDA", False: "FAŁSZ"},
+	"cs": {True: "PRAVDA", False: "NEPRAVDA"},
+	"sk": {True: "PRAVDA", False: "NEPRAVDA"},
+	"hu": {True: "IGAZ", False: "HAMIS"},
+	"ru": {True: "ИСТИНА", False: "ЛОЖЬ"},
+	"uk": {True: "ІСТИНА", False: "ХИБНІСТЬ"},
+	"tr": {True: "DOĞRU", False: "YANLIŞ"},
+	"id": {True: "TRUE", False: "FALSE"},
+	"vi": {True: "TRUE", False: "FALSE"},
+}
+
+// languages writing 1234.56 as 1,234.56, the others write it as 1.234,56 or 1 234,56
+var dotDecimalLanguages = map[string]bool{"en": true, "ja": true, "zh": true, "ko": true, "th": true, "he": true}
+
+// regions writing numbers unlike the other regions of their language
+var regionNumbers = map[string]Locale{
+	"de_CH": {DecimalSeparator: ".", GroupSeparators: []string{"'", "’"}},
+	"it_CH": {DecimalSeparator: ".", GroupSeparators: []string{"'", "’"}},
+	"fr_CH": {DecimalSeparator: ".", GroupSeparators: []string{"'", "’"}},
+	"es_MX": englishNumbers,
+	"es_US": englishNumbers,
+	"en_ZA": {DecimalSeparator: ",", GroupSeparators: spaceGroup},
+	"pt_PT": {DecimalSeparator: ",", GroupSeparators: spaceGroup},
+}
+
+// LookupLocale returns the locale of a locale name, e.g. de_DE, de-DE or de. The second result is false for unknown languages.
+func LookupLocale(name string) (Locale, bool) {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "-", "_")
+	language := strings.ToLower(strings.SplitN(name, "_", 2)[0])
+	region := ""
+	if parts := strings.SplitN(name, "_", 2); len(parts) == 2 {
+		region = strings.ToUpper(parts[1])
+	}
+
+	locale, ok := locales[language]
+	if !ok {
+		if !dotDecimalLanguages[language] {
+			return Locale{}, false
+		}
+		locale = locales["en"]
+	}
+	locale.Name = language
+	if region != "" {
+		locale.Name += "_" + region
+	}
+
+	switch {
+	case regionNumbers[language+"_"+region].DecimalSeparator != "":
+		numbers := regionNumbers[language+"_"+region]
+		locale.DecimalSeparator, locale.GroupSeparators = numbers.DecimalSeparator, numbers.GroupSeparators
+	case dotDecimalLanguages[language]:
+		locale.DecimalSeparator, locale.GroupSeparators = englishNumbers.DecimalSeparator, englishNumbers.GroupSeparators
+	case spaceGroupLocales[language]:
+		locale.DecimalSeparator, locale.GroupSeparators = ",", spaceGroup
+	default:
+		locale.DecimalSeparator, locale.GroupSeparators = ",", dotGroup
+	}
+	return locale, true
+}
+
+// ParseNumber reads a number written in the locale, e.g. 1.234,56 in de, and returns it as 1234.56
+func (l Locale) ParseNumber(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	sign := ""
+	switch {
+	case strings.HasPrefix(text, "-"), strings.HasPrefix(text, "−"):
+		sign = "-"
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "−")
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+
+	integer, fraction := text, ""
+	if idx := strings.LastIndex(text, l.DecimalSeparator); idx != -1 {
+		integer, fraction = text[:idx], text[idx+len(l.DecimalSeparator):]
+		if fraction == "" || !isDigits(fraction) {
+			return "", false
+		}
+	}
+	if integer == "" {
+		integer = "0"
+	}
+	integer, ok := l.ungroup(integer)
+	if !ok {
+		return "", false
+	}
+
+	number := sign + integer
+	if fraction != "" {
+		number += "." + fraction
+	}
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return "", false
+	}
+	return number, true
+}
+
+// ungroup removes the group separators of the integer part, the groups after the first have 3 digits
+func (l Locale) ungroup(integer string) (string, bool) {
+	if isDigits(integer) {
+		return integer, true
+	}
+	for _, separator := range l.GroupSeparators {
+		if !strings.Contains(integer, separator) {
+			continue
+		}
+		groups := strings.Split(integer, separator)
+		if len(groups[0]) == 0 || len(groups[0]) > 3 || !isDigits(groups[0]) {
+			return "", false
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 || !isDigits(group) {
+				return "", false
+			}
+		}
+		return strings.Join(groups, ""), true
+	}
+	return "", false
+}
+
+// ParsePercent reads a percentage written in the locale, e.g. 12,5 % in fr, and returns its ratio, 0.125
+func (l Locale) ParsePercent(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasSuffix(text, "%") {
+		return "", false
+	}
+	text = strings.TrimRight(strings.TrimSuffix(text, "%"), " "+nbsp+narrowNbsp)
+	number, ok := l.ParseNumber(text)
+	if !ok {
+		return "", false
+	}
+	return shiftDecimalPoint(number, 2), true
+}
+
+// ParseBoolean reads the TRUE & FALSE words of the locale and of English
+func (l Locale) ParseBoolean(text string) (bool, bool) {
+	word := strings.ToUpper(strings.TrimSpace(text))
+	switch {
+	case word == "":
+		return false, false
+	case word == l.True || word == "TRUE":
+		return true, true
+	case word == l.False || word == "FALSE":
+		return false, true
+	}
+	return false, false
+}
+
+// shiftDecimalPoint divides a decimal number by 10^places without rounding errors
+func shiftDecimalPoint(number string, places int) string {
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	integer, fraction := number, ""
+	if idx := strings.Index(number, "."); idx != -1 {
+		integer, fraction = number[:idx], number[idx+1:]
+	}
+	if len(integer) <= places {
+		integer = strings.Repeat("0", places-len(integer)+1) + integer
+	}
+	fraction = strings.TrimRight(integer[len(integer)-places:]+fraction, "0")
+	integer = strings.TrimLeft(integer[:len(integer)-places], "0")
+	if integer == "" {
+		integer = "0"
+	}
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction
+}
+
+func isDigits(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
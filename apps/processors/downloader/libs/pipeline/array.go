@@ -0,0 +1,149 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/inference"
+	"downloader/libs/schema"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/samber/lo"
+)
+
+const (
+	DefaultArrayDelimiter = ","
+
+	// a detected multi-select column picks its items from a short list of choices
+	maxDetectedArrayChoices = 50
+	maxDetectedArrayItemLen = 100
+)
+
+// ArrayOptions declares or detects multi-select columns, e.g. Google Sheets chips or comma lists.
+// Their values are split on the delimiter & written as JSON arrays.
+type ArrayOptions struct {
+	ArrayColumns   []string `form:"arrayColumns" json:"arrayColumns"`
+	ArrayDelimiter string   `form:"arrayDelimiter" json:"arrayDelimiter"` // "," when empty
+	DetectArrays   bool     `form:"detectArrays" json:"detectArrays"`
+}
+
+// ArrayColumnStage converts the values of multi-select columns to JSON arrays, the schema inference reads them as Array
+type ArrayColumnStage struct {
+	Options ArrayOptions
+}
+
+func (s *ArrayColumnStage) Name() string {
+	return "array-column"
+}
+
+func (s *ArrayColumnStage) Apply(ctx context.Context, table *Table) error {
+	delimiter := s.Options.ArrayDelimiter
+	if delimiter == "" {
+		delimiter = DefaultArrayDelimiter
+	}
+	for colIdx, col := range table.Columns {
+		if col.Name == IdColumnName {
+			continue
+		}
+		declared := lo.ContainsBy(s.Options.ArrayColumns, func(name string) bool {
+			name = strings.TrimSpace(name)
+			return name == col.Name || name == SafeHeaderName(col.Name)
+		})
+		if !declared && !(s.Options.DetectArrays && isArrayColumn(table, colIdx, delimiter)) {
+			continue
+		}
+		convertArrayColumn(table, colIdx, delimiter)
+	}
+	return nil
+}
+
+func splitArrayValue(value string, delimiter string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, delimiter) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isArrayColumn detects text columns whose values are lists of items repeated across the rows
+func isArrayColumn(table *Table, colIdx int, delimiter string) bool {
+	listCount, itemCount := 0, 0
+	choices := make(map[string]bool)
+	for _, row := range table.Rows {
+		cell := row[colIdx]
+		if cell.IsEmpty() {
+			continue
+		}
+		if cell.Type != StringCell {
+			return false
+		}
+		if strings.Contains(cell.Value, delimiter) {
+			listCount++
+		}
+		for _, item := range splitArrayValue(cell.Value, delimiter) {
+			if len(item) > maxDetectedArrayItemLen {
+				return false
+			}
+			itemCount++
+			choices[item] = true
+			if len(choices) > maxDetectedArrayChoices {
+				return false
+			}
+		}
+	}
+	return listCount >= 2 && len(choices)*2 <= itemCount
+}
+
+func convertArrayColumn(table *Table, colIdx int, delimiter string) {
+	itemsByRow := make([][]string, len(table.Rows))
+	inferrer := inference.NewColumnInferrer(inference.Options{})
+	for rowIdx, row := range table.Rows {
+		cell := row[colIdx]
+		if cell.IsEmpty() || cell.Type == ErrorCell {
+			continue
+		}
+		itemsByRow[rowIdx] = splitArrayValue(cell.Value, delimiter)
+		for _, item := range itemsByRow[rowIdx] {
+			inferrer.Add(inference.ClassifyString(item))
+		}
+	}
+	itemType := inferrer.Result().Type
+
+	for rowIdx, row := range table.Rows {
+		items := itemsByRow[rowIdx]
+		if items == nil {
+			continue
+		}
+		if len(items) == 0 {
+			row[colIdx] = Cell{Type: EmptyCell}
+			continue
+		}
+		value, err := jsoniter.MarshalToString(lo.Map(items, func(item string, _ int) interface{} {
+			return arrayItemValue(item, itemType)
+		}))
+		if err != nil {
+			continue
+		}
+		row[colIdx] = Cell{Type: StringCell, Value: value, Raw: row[colIdx].Raw}
+	}
+}
+
+// arrayItemValue types the item of a JSON array, numbers & booleans are not quoted
+func arrayItemValue(item string, itemType schema.DataType) interface{} {
+	switch itemType {
+	case schema.Integer, schema.Decimal:
+		if number, err := strconv.ParseInt(item, 10, 64); err == nil {
+			return number
+		}
+		if number, err := strconv.ParseFloat(item, 64); err == nil {
+			return number
+		}
+	case schema.Boolean:
+		if value := inference.ClassifyString(item); value.Kind == inference.KindBoolean {
+			return value.BooleanValue
+		}
+	}
+	return item
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/schema"
+	"downloader/util"
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	excelize "github.com/xuri/excelize/v2"
+)
+
+const CellMetaColumnName = schema.OriginalCellMetaFieldName
+
+// CaptureOptions selects the cell details read with the values, they are written to the cell meta column of the snapshot
+type CaptureOptions struct {
+	CaptureHyperlinks bool `form:"captureHyperlinks" json:"captureHyperlinks"`
+	CaptureNotes      bool `form:"captureNotes" json:"captureNotes"`
+	CaptureFormulas   bool `form:"captureFormulas" json:"captureFormulas"`
+}
+
+func (o CaptureOptions) Enabled() bool {
+	return o.CaptureHyperlinks || o.CaptureNotes || o.CaptureFormulas
+}
+
+type CellMeta struct {
+	Hyperlink string `json:"hyperlink,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Formula   string `json:"formula,omitempty"`
+}
+
+func (m *CellMeta) IsEmpty() bool {
+	return m == nil || (m.Hyperlink == "" && m.Note == "" && m.Formula == "")
+}
+
+// CellRef is the 1-based sheet coordinates of a cell
+type CellRef struct {
+	Row, Col int
+}
+
+// captureCellMeta reads the selected details of the cells of the table
+func captureCellMeta(file *excelize.File, sheetName string, table *Table, options CaptureOptions) error {
+	notes := make(map[string]string)
+	if options.CaptureNotes {
+		comments, err := file.GetComments(sheetName)
+		if err != nil {
+			return fmt.Errorf("Error when reading notes of sheet %s: %w", sheetName, err)
+		}
+		for _, comment := range comments {
+			text := comment.Text
+			for _, run := range comment.Paragraph {
+				text += run.Text
+			}
+			notes[comment.Cell] = strings.TrimSpace(text)
+		}
+	}
+
+	table.CellMeta = make(map[CellRef]*CellMeta)
+	for rowIdx, row := range table.Rows {
+		for colIdx, col := range table.Columns {
+			ref := CellRef{Row: table.RowNumber(rowIdx), Col: col.SourceIndex}
+			axis, err := excelize.CoordinatesToCellName(ref.Col, ref.Row)
+			if err != nil {
+				return err
+			}
+			meta := &CellMeta{Note: notes[axis]}
+			// empty cells have no link or formula worth reading
+			if !row[colIdx].IsEmpty() {
+				if options.CaptureHyperlinks {
+					if _, meta.Hyperlink, err = file.GetCellHyperLink(sheetName, axis); err != nil {
+						return fmt.Errorf("Error when reading hyperlink of cell %s: %w", axis, err)
+					}
+				}
+				if options.CaptureFormulas {
+					if meta.Formula, err = file.GetCellFormula(sheetName, axis); err != nil {
+						return fmt.Errorf("Error when reading formula of cell %s: %w", axis, err)
+					}
+				}
+			}
+			if !meta.IsEmpty() {
+				table.CellMeta[ref] = meta
+			}
+		}
+	}
+	return nil
+}
+
+// #########################################################################################################
+
+// CellMetaStage adds the cell meta column, it holds the captured details of the cells of the row keyed by hashed field name
+type CellMetaStage struct {
+	Enabled bool
+}
+
+func (s *CellMetaStage) Name() string {
+	return "cell-meta"
+}
+
+func (s *CellMetaStage) Apply(ctx context.Context, table *Table) error {
+	if !s.Enabled {
+		return nil
+	}
+	hashedNames := make([]string, len(table.Columns))
+	for idx, col := range table.Columns {
+		hashedNames[idx] = util.HashFieldName(col.Name)
+	}
+	table.AppendColumn(Column{Name: CellMetaColumnName})
+	metaColIdx := len(table.Columns) - 1
+
+	for rowIdx, row := range table.Rows {
+		rowMeta := make(map[string]*CellMeta)
+		for idx, col := range table.Columns[:metaColIdx] {
+			// generated columns have no source cell
+			if col.SourceIndex == 0 {
+				continue
+			}
+			if meta := table.CellMeta[CellRef{Row: table.RowNumber(rowIdx), Col: col.SourceIndex}]; !meta.IsEmpty() {
+				rowMeta[hashedNames[idx]] = meta
+			}
+		}
+		if len(rowMeta) == 0 {
+			continue
+		}
+		value, err := jsoniter.MarshalToString(rowMeta)
+		if err != nil {
+			return fmt.Errorf("Error when marshalling cell meta: %w", err)
+		}
+		row[metaColIdx] = Cell{Type: StringCell, Value: value, Raw: value}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"downloader/libs/inference"
+	"downloader/pkg/e"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOptions sets how the dates written as text are read, the dates of date cells are not ambiguous
+type DateOptions struct {
+	// DMY, MDY or YMD, detected from the values when empty
+	DateOrder string `form:"dateOrder" json:"dateOrder"`
+	// strftime formats of columns, e.g. "Due date=%m/%d/%Y"
+	DateFormats []string `form:"dateFormats" json:"dateFormats"`
+}
+
+type dateHint struct {
+	format  string
+	layout  string
+	hasTime bool
+}
+
+type dateSettings struct {
+	order inference.DateOrder
+	hints map[string]dateHint
+}
+
+func (o DateOptions) settings() (dateSettings, error) {
+	order, err := inference.ParseDateOrder(o.DateOrder)
+	if err != nil {
+		return dateSettings{}, e.NewExternalErrorWithDescription(e.DATE_OPTIONS_INVALID, "Invalid date options", err.Error())
+	}
+	settings := dateSettings{order: order, hints: make(map[string]dateHint)}
+	for _, columnFormat := range o.DateFormats {
+		idx := strings.LastIndex(columnFormat, "=")
+		if idx == -1 {
+			return dateSettings{}, e.NewExternalErrorWithDescription(e.DATE_OPTIONS_INVALID, "Invalid date options", fmt.Sprintf("date format %s is not column=format", columnFormat))
+		}
+		column, format := strings.TrimSpace(columnFormat[:idx]), strings.TrimSpace(columnFormat[idx+1:])
+		layout, hasTime, err := inference.StrftimeLayout(format)
+		if err != nil {
+			return dateSettings{}, e.NewExternalErrorWithDescription(e.DATE_OPTIONS_INVALID, "Invalid date options", err.Error())
+		}
+		settings.hints[SafeHeaderName(column)] = dateHint{format: format, layout: layout, hasTime: hasTime}
+	}
+	return settings, nil
+}
+
+func (s dateSettings) hint(columnName string) (dateHint, bool) {
+	hint, ok := s.hints[SafeHeaderName(columnName)]
+	return hint, ok
+}
+
+// parseTextDates reads the text cells of a column as dates, with the format hint of the column or else with the date order.
+// It returns nil when a text cell is not a date, or when the column has no text cell or holds other values than dates.
+func (s dateSettings) parseTextDates(table *Table, colIdx int, location *time.Location) (map[int]time.Time, string) {
+	textRows := make([]int, 0)
+	for rowIdx, row := range table.Rows {
+		switch row[colIdx].Type {
+		case StringCell:
+			textRows = append(textRows, rowIdx)
+		case EmptyCell, ErrorCell, DateCell:
+		default:
+			return nil, ""
+		}
+	}
+	if len(textRows) == 0 {
+		return nil, ""
+	}
+
+	dates := make(map[int]time.Time, len(textRows))
+	if hint, ok := s.hint(table.Columns[colIdx].Name); ok {
+		for _, rowIdx := range textRows {
+			date, err := time.ParseInLocation(hint.layout, table.Rows[rowIdx][colIdx].Value, location)
+			if err != nil {
+				return nil, ""
+			}
+			dates[rowIdx] = date.In(location)
+		}
+		return dates, hint.format
+	}
+
+	numericDates := make([]inference.NumericDate, len(textRows))
+	for idx, rowIdx := range textRows {
+		numericDate, ok := inference.ParseNumericDate(table.Rows[rowIdx][colIdx].Value)
+		if !ok {
+			return nil, ""
+		}
+		numericDates[idx] = numericDate
+	}
+	order, ok := inference.DetectDateOrder(numericDates, s.order)
+	if !ok {
+		return nil, ""
+	}
+	for idx, rowIdx := range textRows {
+		dates[rowIdx], _ = numericDates[idx].Time(order, location)
+	}
+	return dates, numericDates[0].Format(order)
+}
+
+// textDateCell is the date cell of a date written as text, its raw value is the serial number of the date
+func textDateCell(date time.Time) Cell {
+	days := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Sub(time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	clock := time.Duration(date.Hour())*time.Hour + time.Duration(date.Minute())*time.Minute + time.Duration(date.Second())*time.Second + time.Duration(date.Nanosecond())
+	return Cell{
+		Type:  DateCell,
+		Value: date.UTC().Format("2006-01-02T15:04:05.999Z"),
+		Raw:   strconv.FormatFloat(days+float64(clock)/nanosInADay, 'f', -1, 64),
+	}
+}
@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+const fixtureSheet = "Sheet1"
+
+// rawCell is a cell written as is in the sheet xml, for the cell types excelize cannot write: errors, formula texts & inline strings
+type rawCell struct {
+	Type  string // t attribute of the cell: e, str or inlineStr
+	Value string
+}
+
+// writeFixture writes a xlsx file built by build, the header row is written first
+func writeFixture(t *testing.T, header []string, build func(f *excelize.File)) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for idx, name := range header {
+		cell, _ := excelize.CoordinatesToCellName(idx+1, 1)
+		if err := f.SetCellStr(fixtureSheet, cell, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if build != nil {
+		build(f)
+	}
+	filePath := filepath.Join(t.TempDir(), "fixture.xlsx")
+	if err := f.SaveAs(filePath); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+// setRawCells replaces cells of the first sheet of the file, the cells must have been written before
+func setRawCells(t *testing.T, filePath string, cells map[string]rawCell) {
+	t.Helper()
+	patchSheetXml(t, filePath, func(content []byte) []byte {
+		for ref, cell := range cells {
+			cellRegex := regexp.MustCompile(fmt.Sprintf(`<c r="%s"[^>]*?(/>|>.*?</c>)`, ref))
+			if !cellRegex.Match(content) {
+				t.Fatalf("cell %s not found in fixture", ref)
+			}
+			value := fmt.Sprintf("<v>%s</v>", cell.Value)
+			if cell.Type == "inlineStr" {
+				value = fmt.Sprintf("<is><t>%s</t></is>", cell.Value)
+			}
+			content = cellRegex.ReplaceAll(content, []byte(fmt.Sprintf(`<c r="%s" t="%s">%s</c>`, ref, cell.Type, value)))
+		}
+		return content
+	})
+}
+
+// setDimension sets the used range recorded for the first sheet of the file, excelize does not update it on save
+func setDimension(t *testing.T, filePath string, ref string) {
+	t.Helper()
+	dimensionRegex := regexp.MustCompile(`<dimension ref="[^"]*"\s*(/>|></dimension>)`)
+	patchSheetXml(t, filePath, func(content []byte) []byte {
+		if !dimensionRegex.Match(content) {
+			t.Fatal("dimension not found in fixture")
+		}
+		return dimensionRegex.ReplaceAll(content, []byte(fmt.Sprintf(`<dimension ref="%s"/>`, ref)))
+	})
+}
+
+// patchSheetXml rewrites the xml of the first sheet of the file
+func patchSheetXml(t *testing.T, filePath string, patch func(content []byte) []byte) {
+	t.Helper()
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, file := range reader.File {
+		content, err := readZipFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			content = patch(content)
+		}
+		entry, err := writer.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reader.Close()
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// readFixture reads the sheet of the fixture with the default options
+func readFixture(t *testing.T, filePath string) *Table {
+	t.Helper()
+	table, err := ReadXlsxSheet(filePath, fixtureSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"downloader/pkg/e"
+	"fmt"
+	"strings"
+)
+
+const DefaultHeaderSeparator = " / "
+
+// HeaderOptions locates the header in the region: the leading SkipRows rows are dropped,
+// then the header starts at HeaderRow and its HeaderRowCount rows are merged into the column names,
+// e.g. "Q1 / Revenue". The zero value is a single header row on the first row.
+type HeaderOptions struct {
+	SkipRows        int    `form:"skipRows" json:"skipRows"`
+	HeaderRow       int    `form:"headerRow" json:"headerRow"` // 1-based, after the skipped rows
+	HeaderRowCount  int    `form:"headerRowCount" json:"headerRowCount"`
+	HeaderSeparator string `form:"headerSeparator" json:"headerSeparator"`
+}
+
+func (o HeaderOptions) withDefaults() HeaderOptions {
+	if o.HeaderRow == 0 {
+		o.HeaderRow = 1
+	}
+	if o.HeaderRowCount == 0 {
+		o.HeaderRowCount = 1
+	}
+	if o.HeaderSeparator == "" {
+		o.HeaderSeparator = DefaultHeaderSeparator
+	}
+	return o
+}
+
+func (o HeaderOptions) Validate() error {
+	if o.SkipRows < 0 || o.HeaderRow < 0 || o.HeaderRowCount < 0 {
+		return e.NewExternalErrorWithDescription(e.HEADER_OPTIONS_INVALID, "Invalid header options", "skipRows, headerRow & headerRowCount cannot be negative")
+	}
+	return nil
+}
+
+// FirstHeaderRow returns the row number of the first header row, for a region starting at firstRow
+func (o HeaderOptions) FirstHeaderRow(firstRow int) int {
+	o = o.withDefaults()
+	return firstRow + o.SkipRows + o.HeaderRow - 1
+}
+
+// LastHeaderRow returns the row number of the last header row, the data starts on the next row
+func (o HeaderOptions) LastHeaderRow(firstRow int) int {
+	o = o.withDefaults()
+	return o.FirstHeaderRow(firstRow) + o.HeaderRowCount - 1
+}
+
+// MergeHeaderRows joins the non empty values of each column of the header rows,
+// a value repeated by vertically merged cells is kept once
+func MergeHeaderRows(rows [][]string, separator string) []string {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	header := make([]string, width)
+	for col := range header {
+		parts := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if col >= len(row) {
+				continue
+			}
+			value := strings.TrimSpace(row[col])
+			if value == "" || (len(parts) > 0 && parts[len(parts)-1] == value) {
+				continue
+			}
+			parts = append(parts, value)
+		}
+		header[col] = strings.Join(parts, separator)
+	}
+	return header
+}
+
+// fillMergedCells copies the value of merged cells to every cell of the merge in the given rows (0-based)
+func fillMergedCells(rows [][]string, mergedCells []mergedCell, firstRow int) {
+	for _, merged := range mergedCells {
+		for r := merged.firstRow; r <= merged.lastRow; r++ {
+			idx := r - firstRow
+			if idx < 0 || idx >= len(rows) {
+				continue
+			}
+			for c := merged.firstCol; c <= merged.lastCol; c++ {
+				for len(rows[idx]) <= c {
+					rows[idx] = append(rows[idx], "")
+				}
+				rows[idx][c] = merged.value
+			}
+		}
+	}
+}
+
+// 0-based coordinates of merged cells
+type mergedCell struct {
+	firstRow, firstCol int
+	lastRow, lastCol   int
+	value              string
+}
+
+func parseMergedCell(start, end, value string) (mergedCell, error) {
+	startRef, err := parseA1Ref(start)
+	if err != nil {
+		return mergedCell{}, err
+	}
+	endRef, err := parseA1Ref(end)
+	if err != nil {
+		return mergedCell{}, err
+	}
+	if startRef.row == 0 || startRef.col == 0 || endRef.row == 0 || endRef.col == 0 {
+		return mergedCell{}, fmt.Errorf("Invalid merged cell %s:%s", start, end)
+	}
+	return mergedCell{
+		firstRow: startRef.row - 1,
+		firstCol: startRef.col - 1,
+		lastRow:  endRef.row - 1,
+		lastCol:  endRef.col - 1,
+		value:    value,
+	}, nil
+}
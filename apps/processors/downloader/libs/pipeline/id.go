@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/pkg/e"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type IdFix struct {
+	Row int // 1-based sheet row number
+	Id  string
+}
+
+type IdWriteRequest struct {
+	Column    int  // 1-based sheet column of the id column
+	NewColumn bool // the sheet had no id column, the header cell is included in Fixes
+	Fixes     []IdFix
+	Hide      bool // hide & protect the id column, even without fixes
+}
+
+// IdOptions sets how the id column is written to the spreadsheet
+type IdOptions struct {
+	// hide the id column, Google Sheets also warns before editing it
+	HideIdColumn bool `form:"hideIdColumn" json:"hideIdColumn"`
+}
+
+// IdWriter writes the repaired ids back to the spreadsheet
+type IdWriter interface {
+	WriteIds(ctx context.Context, request IdWriteRequest) error
+}
+
+// IdColumnStage makes sure every row has a unique uuid in the id column.
+// The id column is moved to the end of the table, missing or invalid ids are
+// regenerated and written back to the spreadsheet through Writer.
+// With key columns, the ids are hashed from the keys & the id column of the sheet is ignored.
+type IdColumnStage struct {
+	Writer  IdWriter
+	Keys    KeyOptions
+	Options IdOptions
+
+	// result
+	Request IdWriteRequest
+}
+
+func (s *IdColumnStage) Name() string {
+	return "id-column"
+}
+
+func (s *IdColumnStage) Apply(ctx context.Context, table *Table) error {
+	if s.Keys.Enabled() {
+		return s.applyKeys(table)
+	}
+
+	idColIdx := table.ColumnIndex(IdColumnName)
+	newColumn := idColIdx == -1
+	if newColumn && !table.NextColumnFree && s.Writer != nil {
+		return e.NewExternalErrorWithDescription(e.ID_COL_NO_SPACE, "No empty column for the id column", fmt.Sprintf("The column after the data region must be empty to add the id column (%s)", IdColumnName))
+	}
+	if newColumn {
+		maxSourceIndex := 0
+		for _, col := range table.Columns {
+			if col.SourceIndex > maxSourceIndex {
+				maxSourceIndex = col.SourceIndex
+			}
+		}
+		table.AppendColumn(Column{Name: IdColumnName, SourceIndex: maxSourceIndex + 1})
+	} else {
+		// move id column to the end
+		order := make([]int, 0, len(table.Columns))
+		for idx := range table.Columns {
+			if idx != idColIdx {
+				order = append(order, idx)
+			}
+		}
+		table.SelectColumns(append(order, idColIdx))
+	}
+	idColIdx = len(table.Columns) - 1
+
+	fixes := RepairIds(table, idColIdx)
+	if newColumn {
+		fixes = append([]IdFix{{Row: table.HeaderRow, Id: IdColumnName}}, fixes...)
+	}
+	s.Request = IdWriteRequest{
+		Column:    table.Columns[idColIdx].SourceIndex,
+		NewColumn: newColumn,
+		Fixes:     fixes,
+		Hide:      s.Options.HideIdColumn,
+	}
+
+	if (len(fixes) == 0 && !s.Request.Hide) || s.Writer == nil {
+		return nil
+	}
+	if err := s.Writer.WriteIds(ctx, s.Request); err != nil {
+		return fmt.Errorf("Error when writing ids: %w", err)
+	}
+	return nil
+}
+
+func (s *IdColumnStage) applyKeys(table *Table) error {
+	if idColIdx := table.ColumnIndex(IdColumnName); idColIdx != -1 {
+		order := make([]int, 0, len(table.Columns)-1)
+		for idx := range table.Columns {
+			if idx != idColIdx {
+				order = append(order, idx)
+			}
+		}
+		table.SelectColumns(order)
+	}
+	keyColIndexes, err := s.Keys.keyColumnIndexes(table)
+	if err != nil {
+		return err
+	}
+	table.AppendColumn(Column{Name: IdColumnName})
+	return HashKeyIds(table, keyColIndexes, len(table.Columns)-1)
+}
+
+// RepairIds regenerates empty, duplicated & invalid ids of the column, returns the changed rows
+func RepairIds(table *Table, idColIdx int) []IdFix {
+	fixes := make([]IdFix, 0)
+	idSet := make(map[string]bool, len(table.Rows))
+	for rowIdx, row := range table.Rows {
+		id := row[idColIdx].Value
+		if row[idColIdx].IsEmpty() || idSet[id] || !isValidUUID(id) {
+			id = uuid.New().String()
+			fixes = append(fixes, IdFix{Row: table.RowNumber(rowIdx), Id: id})
+		}
+		idSet[id] = true
+		row[idColIdx] = Cell{Type: StringCell, Value: id, Raw: id}
+	}
+	return fixes
+}
+
+func isValidUUID(u string) bool {
+	_, err := uuid.Parse(u)
+	return err == nil
+}
+
+// GroupIdFixes splits fixes into batches of consecutive rows, map[firstRowNumber][]id
+func GroupIdFixes(fixes []IdFix, maxBatchSize int) map[int][]string {
+	batches := make(map[int][]string)
+	var firstRow, prevRow int
+	var batch []string
+	for _, fix := range fixes {
+		if batch != nil && prevRow+1 == fix.Row && len(batch) < maxBatchSize {
+			batch = append(batch, fix.Id)
+		} else {
+			if batch != nil {
+				batches[firstRow] = batch
+			}
+			firstRow = fix.Row
+			batch = []string{fix.Id}
+		}
+		prevRow = fix.Row
+	}
+	if batch != nil {
+		batches[firstRow] = batch
+	}
+	return batches
+}
@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	excelize "github.com/xuri/excelize/v2"
+)
+
+func TestRepairIds(t *testing.T) {
+	validId := uuid.New().String()
+	tests := []struct {
+		name      string
+		ids       []string // ids of the sheet rows, empty for an empty cell
+		wantFixed []int    // sheet row numbers of the regenerated ids
+	}{
+		{name: "valid ids", ids: []string{validId, uuid.New().String()}, wantFixed: []int{}},
+		{name: "empty id", ids: []string{validId, ""}, wantFixed: []int{3}},
+		{name: "duplicated id", ids: []string{validId, validId}, wantFixed: []int{3}},
+		{name: "invalid id", ids: []string{"abc", validId}, wantFixed: []int{2}},
+		{name: "numeric id", ids: []string{"123", validId}, wantFixed: []int{2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeFixture(t, []string{"name", IdColumnName}, func(f *excelize.File) {
+				for idx, id := range test.ids {
+					f.SetCellStr(fixtureSheet, fmt.Sprintf("A%d", idx+2), "row")
+					if id != "" {
+						f.SetCellStr(fixtureSheet, fmt.Sprintf("B%d", idx+2), id)
+					}
+				}
+			})
+			table := readFixture(t, filePath)
+			idColIdx := table.ColumnIndex(IdColumnName)
+			before := make([]string, len(table.Rows))
+			for idx, row := range table.Rows {
+				before[idx] = row[idColIdx].Value
+			}
+
+			fixes := RepairIds(table, idColIdx)
+			if len(fixes) != len(test.wantFixed) {
+				t.Fatalf("got fixes %+v, want rows %v", fixes, test.wantFixed)
+			}
+			for idx, fix := range fixes {
+				if fix.Row != test.wantFixed[idx] {
+					t.Errorf("got fixed row %d, want %d", fix.Row, test.wantFixed[idx])
+				}
+				if got := table.Rows[fix.Row-table.HeaderRow-1][idColIdx].Value; got != fix.Id {
+					t.Errorf("row %d: got id %q in the table, want the fixed id %q", fix.Row, got, fix.Id)
+				}
+			}
+			seen := make(map[string]bool)
+			for idx, row := range table.Rows {
+				id := row[idColIdx].Value
+				if !isValidUUID(id) || seen[id] {
+					t.Errorf("row %d: got invalid or duplicated id %q", table.RowNumber(idx), id)
+				}
+				seen[id] = true
+				if isValidUUID(before[idx]) && !lo.Contains(test.wantFixed, table.RowNumber(idx)) && id != before[idx] {
+					t.Errorf("row %d: valid id %q changed to %q", table.RowNumber(idx), before[idx], id)
+				}
+			}
+		})
+	}
+}
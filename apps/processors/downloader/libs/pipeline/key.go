@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"downloader/pkg/e"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// maximum number of rows listed in the blank & duplicated key errors
+const maxReportedKeyRows = 10
+
+// namespace of the ids hashed from natural keys
+var naturalKeyNamespace = uuid.MustParse("6f1c3b2e-8a4d-5e7f-9b0c-2d4e6f8a1c3e")
+
+// KeyOptions selects the columns identifying a row. When set, the id of a row is hashed from
+// its key values instead of being read from the id column, nothing is written to the spreadsheet.
+type KeyOptions struct {
+	KeyColumns []string `form:"keyColumns" json:"keyColumns"`
+}
+
+func (o KeyOptions) Enabled() bool {
+	return len(o.KeyColumns) > 0
+}
+
+// keyColumnIndexes returns the indexes of the key columns, matched by their safe header name
+func (o KeyOptions) keyColumnIndexes(table *Table) ([]int, error) {
+	indexes := make([]int, 0, len(o.KeyColumns))
+	selected := make(map[int]bool, len(o.KeyColumns))
+	for _, name := range o.KeyColumns {
+		colIdx := table.ColumnIndex(SafeHeaderName(name))
+		if colIdx == -1 || name == IdColumnName {
+			return nil, e.NewExternalErrorWithDescription(e.KEY_OPTIONS_INVALID, "Invalid key columns", fmt.Sprintf("Key column %s not found in header", name))
+		}
+		if selected[colIdx] {
+			return nil, e.NewExternalErrorWithDescription(e.KEY_OPTIONS_INVALID, "Invalid key columns", fmt.Sprintf("Key column %s is selected more than once", name))
+		}
+		selected[colIdx] = true
+		indexes = append(indexes, colIdx)
+	}
+	return indexes, nil
+}
+
+// HashKeyIds sets the id column to the uuid hashed from the key columns of each row.
+// Rows without any key value & rows sharing their key with another row are rejected.
+func HashKeyIds(table *Table, keyColIndexes []int, idColIdx int) error {
+	blankRows := make([]int, 0)
+	duplicatedRows := make([]string, 0)
+	keyRows := make(map[string]int, len(table.Rows))
+	for rowIdx, row := range table.Rows {
+		values := make([]string, len(keyColIndexes))
+		blank := true
+		for idx, colIdx := range keyColIndexes {
+			if cell := row[colIdx]; !cell.IsEmpty() && cell.Type != ErrorCell {
+				values[idx] = cell.Value
+				blank = false
+			}
+		}
+		if blank {
+			blankRows = append(blankRows, table.RowNumber(rowIdx))
+			continue
+		}
+		key, err := jsoniter.MarshalToString(values)
+		if err != nil {
+			return fmt.Errorf("Error when marshalling key: %w", err)
+		}
+		if firstRow, ok := keyRows[key]; ok {
+			duplicatedRows = append(duplicatedRows, fmt.Sprintf("%d & %d", firstRow, table.RowNumber(rowIdx)))
+			continue
+		}
+		keyRows[key] = table.RowNumber(rowIdx)
+
+		id := uuid.NewSHA1(naturalKeyNamespace, []byte(key)).String()
+		row[idColIdx] = Cell{Type: StringCell, Value: id, Raw: id}
+	}
+
+	if len(blankRows) > 0 {
+		return e.NewExternalErrorWithDescription(e.ID_KEY_BLANK, "Rows without key", fmt.Sprintf("%d rows have no value in the key columns, rows %s", len(blankRows), reportedRows(blankRows)))
+	}
+	if len(duplicatedRows) > 0 {
+		return e.NewExternalErrorWithDescription(e.ID_KEY_DUPLICATED, "Duplicated keys", fmt.Sprintf("%d rows have the key of a previous row, rows %s", len(duplicatedRows), strings.Join(truncateRows(duplicatedRows), ", ")))
+	}
+	return nil
+}
+
+func reportedRows(rows []int) string {
+	names := make([]string, len(rows))
+	for idx, row := range rows {
+		names[idx] = fmt.Sprint(row)
+	}
+	return strings.Join(truncateRows(names), ", ")
+}
+
+func truncateRows(rows []string) []string {
+	if len(rows) <= maxReportedKeyRows {
+		return rows
+	}
+	return append(rows[:maxReportedKeyRows:maxReportedKeyRows], "...")
+}
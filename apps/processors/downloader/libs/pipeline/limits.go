@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"downloader/pkg/e"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+// Limits are the maximum sizes of an ingested sheet, 0 for no limit.
+// The file size & the rows & columns recorded in the xlsx are checked before reading the cells.
+type Limits struct {
+	MaxRows       int
+	MaxColumns    int
+	MaxCellLength int // characters of the formatted value
+	MaxFileBytes  int64
+}
+
+func (l Limits) checkFileSize(filePath string) error {
+	if l.MaxFileBytes == 0 {
+		return nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("Error when reading size of xlsx file: %w", err)
+	}
+	if info.Size() > l.MaxFileBytes {
+		return e.NewExternalErrorWithDescription(e.FILE_SIZE_LIMIT_EXCEEDED, "File is too large", fmt.Sprintf("The file has %d bytes, the limit is %d bytes", info.Size(), l.MaxFileBytes))
+	}
+	return nil
+}
+
+// checkDimension checks the rows & columns of the used range the xlsx records for the sheet, cut to the region.
+// Sheets without recorded dimension are only checked on the cells read.
+func (l Limits) checkDimension(file *excelize.File, sheetName string, region bounds, lastHeaderRow int) error {
+	if l.MaxRows == 0 && l.MaxColumns == 0 {
+		return nil
+	}
+	ref, err := file.GetSheetDimension(sheetName)
+	if err != nil {
+		return fmt.Errorf("Error when reading dimension of sheet %s: %w", sheetName, err)
+	}
+	if ref == "" {
+		return nil
+	}
+	dimension, err := parseA1Range(ref)
+	if err != nil {
+		return nil
+	}
+
+	lastCol := dimension.lastCol
+	if lastCol == 0 {
+		lastCol = dimension.firstCol
+	}
+	// the id column written next to the region is read with it
+	if region.lastCol != 0 && region.lastCol+1 < lastCol {
+		lastCol = region.lastCol + 1
+	}
+	if err := l.checkColumns(lastCol - region.firstCol + 1); err != nil {
+		return err
+	}
+
+	lastRow := dimension.lastRow
+	if lastRow == 0 {
+		lastRow = dimension.firstRow
+	}
+	if region.lastRow != 0 && region.lastRow < lastRow {
+		lastRow = region.lastRow
+	}
+	return l.checkRows(lastRow - lastHeaderRow)
+}
+
+func (l Limits) checkRows(count int) error {
+	if l.MaxRows != 0 && count > l.MaxRows {
+		return e.NewExternalErrorWithDescription(e.ROW_LIMIT_EXCEEDED, "Sheet has too many rows", fmt.Sprintf("The sheet has %d rows, the limit is %d rows", count, l.MaxRows))
+	}
+	return nil
+}
+
+func (l Limits) checkColumns(count int) error {
+	if l.MaxColumns != 0 && count > l.MaxColumns {
+		return e.NewExternalErrorWithDescription(e.COLUMN_LIMIT_EXCEEDED, "Sheet has too many columns", fmt.Sprintf("The sheet has %d columns, the limit is %d columns", count, l.MaxColumns))
+	}
+	return nil
+}
+
+// checkCellLength checks a value of the cell at 1-based col & row
+func (l Limits) checkCellLength(value string, col, row int) error {
+	if l.MaxCellLength == 0 || len(value) <= l.MaxCellLength {
+		return nil
+	}
+	if length := utf8.RuneCountInString(value); length > l.MaxCellLength {
+		cellName, _ := excelize.CoordinatesToCellName(col, row)
+		return e.NewExternalErrorWithDescription(e.CELL_LENGTH_LIMIT_EXCEEDED, "Cell value is too long", fmt.Sprintf("Cell %s has %d characters, the limit is %d characters", cellName, length, l.MaxCellLength))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"downloader/pkg/e"
+	"errors"
+	"testing"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+func TestLimitsCheckDimension(t *testing.T) {
+	// 3 header columns, a stray value in column J of the first data row & 5 data rows
+	filePath := writeFixture(t, []string{"a", "b", "c"}, func(f *excelize.File) {
+		for row := 2; row <= 6; row++ {
+			cell, _ := excelize.CoordinatesToCellName(1, row)
+			f.SetCellValue(fixtureSheet, cell, row)
+		}
+		f.SetCellStr(fixtureSheet, "J2", "note")
+	})
+	setDimension(t, filePath, "A1:J6")
+	tests := []struct {
+		name     string
+		region   Region
+		limits   Limits
+		wantCode int // 0 when the sheet is in the limits
+	}{
+		{name: "no limit", limits: Limits{}},
+		{name: "columns of the used range", limits: Limits{MaxColumns: 5}, wantCode: e.COLUMN_LIMIT_EXCEEDED},
+		{name: "columns without row limit", limits: Limits{MaxColumns: 9, MaxRows: 0}, wantCode: e.COLUMN_LIMIT_EXCEEDED},
+		{name: "columns cut to the region", region: Region{Range: "A:C"}, limits: Limits{MaxColumns: 4}},
+		{name: "rows of the used range", limits: Limits{MaxRows: 4}, wantCode: e.ROW_LIMIT_EXCEEDED},
+		{name: "rows cut to the region", region: Region{Range: "A1:J3"}, limits: Limits{MaxRows: 2, MaxColumns: 10}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadXlsxSheetRegion(filePath, fixtureSheet, test.region, HeaderOptions{}, CaptureOptions{}, test.limits)
+			if test.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("got error %v, want none", err)
+				}
+				return
+			}
+			var externalError *e.ExternalError
+			if !errors.As(err, &externalError) || externalError.Code != test.wantCode {
+				t.Fatalf("got error %v, want code %d", err, test.wantCode)
+			}
+		})
+	}
+}
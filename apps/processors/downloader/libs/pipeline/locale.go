@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/inference"
+	"downloader/pkg/e"
+	"fmt"
+)
+
+// LocaleOptions overrides the locale of the spreadsheet, e.g. de_DE
+type LocaleOptions struct {
+	Locale string `form:"locale" json:"locale"`
+}
+
+// LocaleStage reads the numbers, percentages & booleans written as text in the locale of the spreadsheet,
+// e.g. 1.234,56 & WAHR in de_DE. A column is converted when all its text cells are of one of these kinds.
+// The text is left as is when the locale of the spreadsheet is not known.
+type LocaleStage struct {
+	Options           LocaleOptions
+	SpreadsheetLocale string
+}
+
+func (s *LocaleStage) Name() string {
+	return "parse-locale"
+}
+
+func (s *LocaleStage) Apply(ctx context.Context, table *Table) error {
+	var locale inference.Locale
+	if s.Options.Locale != "" {
+		var ok bool
+		if locale, ok = inference.LookupLocale(s.Options.Locale); !ok {
+			return e.NewExternalErrorWithDescription(e.LOCALE_INVALID, "Invalid locale", fmt.Sprintf("Unknown locale %s", s.Options.Locale))
+		}
+	} else if spreadsheetLocale, ok := inference.LookupLocale(s.SpreadsheetLocale); ok {
+		locale = spreadsheetLocale
+	} else {
+		return nil
+	}
+	for colIdx, col := range table.Columns {
+		if col.Name == IdColumnName {
+			continue
+		}
+		if cells, ok := parseLocaleNumbers(table, colIdx, locale); ok {
+			setColumnCells(table, colIdx, cells)
+		} else if cells, ok := parseLocaleBooleans(table, colIdx, locale); ok {
+			setColumnCells(table, colIdx, cells)
+		}
+	}
+	return nil
+}
+
+func setColumnCells(table *Table, colIdx int, cells map[int]Cell) {
+	for rowIdx, cell := range cells {
+		table.Rows[rowIdx][colIdx] = cell
+	}
+}
+
+// parseLocaleNumbers converts the text cells of a column holding numbers & percentages,
+// the columns of text numbers written the same way in every locale, e.g. 1234, are left to the inference
+func parseLocaleNumbers(table *Table, colIdx int, locale inference.Locale) (map[int]Cell, bool) {
+	cells := make(map[int]Cell)
+	localized := false
+	for rowIdx, row := range table.Rows {
+		cell := row[colIdx]
+		switch cell.Type {
+		case StringCell:
+		case EmptyCell, ErrorCell, NumberCell:
+			continue
+		default:
+			return nil, false
+		}
+		if ratio, ok := locale.ParsePercent(cell.Value); ok {
+			cells[rowIdx] = Cell{Type: NumberCell, Value: ratio, Raw: ratio, NumberFormat: NumberFormat{Kind: PercentNumber, Unit: PercentUnit}}
+			localized = true
+			continue
+		}
+		number, ok := locale.ParseNumber(cell.Value)
+		if !ok {
+			return nil, false
+		}
+		cells[rowIdx] = Cell{Type: NumberCell, Value: normalizeNumber(number), Raw: number}
+		if number != cell.Value {
+			localized = true
+		}
+	}
+	return cells, localized
+}
+
+// parseLocaleBooleans converts the text cells of a column holding TRUE & FALSE words of the locale
+func parseLocaleBooleans(table *Table, colIdx int, locale inference.Locale) (map[int]Cell, bool) {
+	cells := make(map[int]Cell)
+	for rowIdx, row := range table.Rows {
+		cell := row[colIdx]
+		switch cell.Type {
+		case StringCell:
+		case EmptyCell, ErrorCell, BooleanCell:
+			continue
+		default:
+			return nil, false
+		}
+		value, ok := locale.ParseBoolean(cell.Value)
+		if !ok {
+			return nil, false
+		}
+		if value {
+			cells[rowIdx] = Cell{Type: BooleanCell, Value: "true", Raw: "1"}
+		} else {
+			cells[rowIdx] = Cell{Type: BooleanCell, Value: "false", Raw: "0"}
+		}
+	}
+	return cells, len(cells) > 0
+}
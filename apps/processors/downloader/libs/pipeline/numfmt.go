@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"regexp"
+	"strings"
+)
+
+type NumberKind int
+
+const (
+	PlainNumber NumberKind = iota
+	CurrencyNumber
+	PercentNumber
+	DurationNumber
+)
+
+const (
+	PercentUnit  = "ratio" // the value is the stored ratio, 0.25 for 25%
+	DurationUnit = "day"   // the value is the stored number of days
+)
+
+// NumberFormat is the meaning of a number given by the number format of its cell
+type NumberFormat struct {
+	Kind NumberKind
+	Unit string // ISO 4217 code of a currency, empty when unknown
+}
+
+var (
+	// elapsed time tokens, e.g. [h]:mm:ss
+	durationFormatTokenRegex = regexp.MustCompile(`(?i)\[(h+|m+|s+)\]`)
+	// locale tag of a currency, e.g. [$€-407] or [$USD]
+	currencyTagRegex    = regexp.MustCompile(`\[\$([^\]-]*)(-[^\]]*)?\]`)
+	quotedLiteralRegex  = regexp.MustCompile(`"([^"]*)"`)
+	formatLiteralsRegex = regexp.MustCompile(`"[^"]*"|\\.`)
+	currencyCodeRegex   = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// longest symbols first, R$ must be matched before $
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"R$", "BRL"}, {"US$", "USD"}, {"CA$", "CAD"}, {"A$", "AUD"}, {"HK$", "HKD"}, {"S$", "SGD"}, {"NZ$", "NZD"},
+	{"CHF", "CHF"}, {"kr", "SEK"}, {"zł", "PLN"},
+	{"$", "USD"}, {"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"₩", "KRW"}, {"₫", "VND"},
+	{"₽", "RUB"}, {"₺", "TRY"}, {"₪", "ILS"}, {"฿", "THB"}, {"₱", "PHP"}, {"₦", "NGN"}, {"₴", "UAH"},
+}
+
+// built-in number formats of the spreadsheetml spec, the currency symbol of 5-8 & 42, 44 depends on the locale
+var builtInNumberFormats = map[int]NumberFormat{
+	5: {Kind: CurrencyNumber}, 6: {Kind: CurrencyNumber}, 7: {Kind: CurrencyNumber}, 8: {Kind: CurrencyNumber},
+	42: {Kind: CurrencyNumber}, 44: {Kind: CurrencyNumber},
+	9: {Kind: PercentNumber}, 10: {Kind: PercentNumber},
+	46: {Kind: DurationNumber, Unit: DurationUnit},
+}
+
+func builtInNumberFormat(numFmt int) NumberFormat {
+	format, ok := builtInNumberFormats[numFmt]
+	if !ok {
+		return NumberFormat{Kind: PlainNumber}
+	}
+	if format.Kind == PercentNumber {
+		format.Unit = PercentUnit
+	}
+	return format
+}
+
+// parseNumberFormatCode detects currencies, percentages & durations in a custom number format,
+// only the first section (positive numbers) is used
+func parseNumberFormatCode(code string) NumberFormat {
+	section := code
+	if idx := strings.Index(section, ";"); idx != -1 {
+		section = section[:idx]
+	}
+
+	if match := currencyTagRegex.FindStringSubmatch(section); match != nil {
+		return NumberFormat{Kind: CurrencyNumber, Unit: currencyCode(match[1])}
+	}
+	for _, match := range quotedLiteralRegex.FindAllStringSubmatch(section, -1) {
+		if code := currencyCode(strings.TrimSpace(match[1])); code != "" {
+			return NumberFormat{Kind: CurrencyNumber, Unit: code}
+		}
+	}
+
+	unquoted := formatLiteralsRegex.ReplaceAllString(section, "")
+	switch {
+	case durationFormatTokenRegex.MatchString(unquoted):
+		return NumberFormat{Kind: DurationNumber, Unit: DurationUnit}
+	case strings.Contains(unquoted, "%"):
+		return NumberFormat{Kind: PercentNumber, Unit: PercentUnit}
+	}
+	for _, currency := range currencySymbols {
+		if len([]rune(currency.symbol)) == 1 && strings.Contains(unquoted, currency.symbol) {
+			return NumberFormat{Kind: CurrencyNumber, Unit: currency.code}
+		}
+	}
+	return NumberFormat{Kind: PlainNumber}
+}
+
+// currencyCode returns the ISO 4217 code of a currency symbol or code, empty when unknown
+func currencyCode(symbol string) string {
+	if currencyCodeRegex.MatchString(symbol) {
+		return symbol
+	}
+	for _, currency := range currencySymbols {
+		if symbol == currency.symbol {
+			return currency.code
+		}
+	}
+	return ""
+}
+
+func currencyFromFormattedValue(formatted string) string {
+	for _, currency := range currencySymbols {
+		if strings.Contains(formatted, currency.symbol) {
+			return currency.code
+		}
+	}
+	return ""
+}
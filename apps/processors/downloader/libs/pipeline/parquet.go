@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"downloader/util"
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const parquetWriterParallel = 4
+
+// WriteParquet writes the table as a parquet file with hashed column names and string values,
+// empty cells are written as null
+func WriteParquet(w io.Writer, table *Table) error {
+	metadata := make([]string, len(table.Columns))
+	for idx, col := range table.Columns {
+		metadata[idx] = fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", util.HashFieldName(col.Name))
+	}
+	pw, err := writer.NewCSVWriterFromWriter(metadata, w, parquetWriterParallel)
+	if err != nil {
+		return fmt.Errorf("Error when creating parquet writer: %w", err)
+	}
+
+	for _, row := range table.Rows {
+		record := make([]*string, len(row))
+		for idx := range row {
+			if !row[idx].IsEmpty() {
+				record[idx] = &row[idx].Value
+			}
+		}
+		if err := pw.WriteString(record); err != nil {
+			return fmt.Errorf("Error when writing parquet row: %w", err)
+		}
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("Error when finishing parquet file: %w", err)
+	}
+	return nil
+}
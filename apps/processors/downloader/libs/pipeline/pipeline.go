@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/schema"
+	"downloader/pkg/e"
+	"downloader/pkg/job"
+	"errors"
+	"fmt"
+	"shared/storage"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrSheetNotFound = errors.New("Sheet not found")
+
+// SheetOptions are the options of the ingest of a sheet, shared by the requests, the services & the config
+type SheetOptions struct {
+	HeaderOptions
+	ArrayOptions
+	CaptureOptions
+	DateOptions
+	LocaleOptions
+	KeyOptions
+	IdOptions
+	QualityOptions
+}
+
+type Config struct {
+	DataSourceId string
+	SyncVersion  int
+	Timezone     string
+	Region       Region
+	Options      SheetOptions
+	Limits       Limits
+	// locale of the spreadsheet, e.g. de_DE, used when the locale is not overridden
+	SpreadsheetLocale string
+
+	// external error codes of the source
+	SheetEmptyCode    int
+	SheetNotFoundCode int
+
+	IdWriter IdWriter
+	Storage  storage.Storage
+	Logger   *log.Entry
+}
+
+type Result struct {
+	Schema   schema.TableSchema
+	RowCount int
+	// strftime format the dates written as text were read with, by column
+	DateFormats map[string]string
+	// nil without quality rules
+	Quality *QualityReport
+}
+
+// Pipeline ingests a sheet of a xlsx file: it runs the stages on the sheet,
+// then uploads the inferred schema & the parquet snapshot of the data.
+type Pipeline struct {
+	config  Config
+	stages  []Stage
+	quality *QualityStage
+}
+
+func New(config Config) *Pipeline {
+	if config.Logger == nil {
+		config.Logger = log.NewEntry(log.StandardLogger())
+	}
+	quality := &QualityStage{Options: config.Options.QualityOptions}
+	return &Pipeline{
+		config:  config,
+		quality: quality,
+		stages: []Stage{
+			&ValidateHeaderStage{SheetEmptyCode: config.SheetEmptyCode},
+			&TrimGhostCellsStage{},
+			&TrimFieldsStage{},
+			&LocaleStage{Options: config.Options.LocaleOptions, SpreadsheetLocale: config.SpreadsheetLocale},
+			&NormalizeDateStage{Timezone: config.Timezone, Options: config.Options.DateOptions},
+			&ArrayColumnStage{Options: config.Options.ArrayOptions},
+			&SafeHeaderStage{},
+			quality,
+			&IdColumnStage{Writer: config.IdWriter, Keys: config.Options.KeyOptions, Options: config.Options.IdOptions},
+			&ReplaceErrorStage{},
+			&CellMetaStage{Enabled: config.Options.CaptureOptions.Enabled()},
+		},
+	}
+}
+
+func GetDataFileS3Key(dataSourceId string, syncVersion int) string {
+	return fmt.Sprintf("data/%s-%d.parquet", dataSourceId, syncVersion)
+}
+
+func GetSchemaFileS3Key(dataSourceId string, syncVersion int) string {
+	return fmt.Sprintf("schema/%s-%d.json", dataSourceId, syncVersion)
+}
+
+func GetQualityReportS3Key(dataSourceId string, syncVersion int) string {
+	return fmt.Sprintf("quality/%s-%d.json", dataSourceId, syncVersion)
+}
+
+func (p *Pipeline) Load(filePath string, sheetName string) (*Table, error) {
+	table, err := ReadXlsxSheetRegion(filePath, sheetName, p.config.Region, p.config.Options.HeaderOptions, p.config.Options.CaptureOptions, p.config.Limits)
+	if errors.Is(err, ErrSheetNotFound) {
+		return nil, e.NewExternalErrorWithDescription(p.config.SheetNotFoundCode, "Sheet not found", fmt.Sprintf("Sheet %s not found in file", sheetName))
+	}
+	return table, err
+}
+
+func (p *Pipeline) Transform(ctx context.Context, table *Table) error {
+	for _, stage := range p.stages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.config.Logger.Info("Running stage ", stage.Name())
+		job.ReportStage(ctx, stage.Name())
+		if err := stage.Apply(ctx, table); err != nil {
+			return fmt.Errorf("Error in stage %s: %w", stage.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) Upload(ctx context.Context, table *Table, tableSchema schema.TableSchema) error {
+	schemaJson, err := jsoniter.Marshal(tableSchema)
+	if err != nil {
+		return fmt.Errorf("Error when marshalling schema: %w", err)
+	}
+	p.config.Logger.Info("Uploading schema...")
+	err = p.config.Storage.Put(ctx, GetSchemaFileS3Key(p.config.DataSourceId, p.config.SyncVersion), schemaJson, nil)
+	if err != nil {
+		return err
+	}
+
+	p.config.Logger.Info("Uploading data...")
+	// the parquet file is streamed to the storage while it is written
+	dataWriter, err := p.config.Storage.NewWriter(ctx, GetDataFileS3Key(p.config.DataSourceId, p.config.SyncVersion), nil)
+	if err != nil {
+		return err
+	}
+	if err := WriteParquet(dataWriter, table); err != nil {
+		dataWriter.Abort(err)
+		return err
+	}
+	return dataWriter.Close()
+}
+
+func (p *Pipeline) Run(ctx context.Context, filePath string, sheetName string) (*Result, error) {
+	job.ReportStage(ctx, "load")
+	table, err := p.Load(filePath, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	// the report is uploaded & the blocking rules are checked before the ids are written to the sheet
+	p.quality.OnReport = p.uploadQualityReport
+	if err := p.Transform(ctx, table); err != nil {
+		return nil, err
+	}
+
+	p.config.Logger.Info("Inferring schema...")
+	job.ReportStage(ctx, "infer-schema")
+	tableSchema := InferSchema(table)
+
+	job.ReportStage(ctx, "upload")
+	if err := p.Upload(ctx, table, tableSchema); err != nil {
+		return nil, err
+	}
+
+	dateFormats := make(map[string]string)
+	for _, col := range table.Columns {
+		if col.DateFormat != "" {
+			dateFormats[col.Name] = col.DateFormat
+		}
+	}
+	return &Result{
+		Schema:      tableSchema,
+		RowCount:    len(table.Rows),
+		DateFormats: dateFormats,
+		Quality:     p.quality.Report,
+	}, nil
+}
+
+// uploadQualityReport uploads the report of the quality rules, the sync fails after the upload
+// when a blocking rule is not passed so that nothing of the version is written nor loaded
+func (p *Pipeline) uploadQualityReport(ctx context.Context, report *QualityReport) error {
+	reportJson, err := jsoniter.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("Error when marshalling quality report: %w", err)
+	}
+	p.config.Logger.Info("Uploading quality report...")
+	err = p.config.Storage.Put(ctx, GetQualityReportS3Key(p.config.DataSourceId, p.config.SyncVersion), reportJson, nil)
+	if err != nil {
+		return err
+	}
+
+	failures := report.BlockingFailures()
+	if len(failures) == 0 {
+		return nil
+	}
+	descriptions := make([]string, len(failures))
+	for idx, failure := range failures {
+		descriptions[idx] = fmt.Sprintf("%s rule of column %s failed on %d rows", failure.Rule.Kind, failure.Rule.Column, failure.ViolationCount)
+	}
+	return e.NewExternalErrorWithDescription(e.QUALITY_RULE_FAILED, "Blocking quality rule failed", strings.Join(descriptions, ", "))
+}
@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/pkg/e"
+	"errors"
+	"shared/storage"
+	"testing"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+type recordingIdWriter struct {
+	requests []IdWriteRequest
+}
+
+func (w *recordingIdWriter) WriteIds(ctx context.Context, request IdWriteRequest) error {
+	w.requests = append(w.requests, request)
+	return nil
+}
+
+func TestRunQualityRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocking   bool
+		wantErr    bool
+		wantWrites bool
+	}{
+		{name: "blocking rule failed", blocking: true, wantErr: true},
+		{name: "non blocking rule failed", blocking: false, wantWrites: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeFixture(t, []string{"Name", "Age"}, func(f *excelize.File) {
+				f.SetSheetRow(fixtureSheet, "A2", &[]interface{}{"Ann", 30})
+				f.SetSheetRow(fixtureSheet, "A3", &[]interface{}{"", 40})
+			})
+			store, err := storage.NewLocalStorage(t.TempDir(), "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+			writer := &recordingIdWriter{}
+			var options SheetOptions
+			options.QualityRules = []QualityRule{{Column: "Name", Kind: RequiredRule, Blocking: test.blocking}}
+
+			_, err = New(Config{DataSourceId: "ds1", SyncVersion: 1, Options: options, IdWriter: writer, Storage: store}).Run(context.Background(), filePath, fixtureSheet)
+			var externalError *e.ExternalError
+			if test.wantErr != (err != nil) || (test.wantErr && (!errors.As(err, &externalError) || externalError.Code != e.QUALITY_RULE_FAILED)) {
+				t.Fatalf("Run() error = %v, want QUALITY_RULE_FAILED %v", err, test.wantErr)
+			}
+			if got := len(writer.requests) > 0; got != test.wantWrites {
+				t.Errorf("ids written = %v, want %v", got, test.wantWrites)
+			}
+
+			if _, err := store.Stat(context.Background(), GetQualityReportS3Key("ds1", 1)); err != nil {
+				t.Errorf("quality report not uploaded: %v", err)
+			}
+			_, err = store.Stat(context.Background(), GetDataFileS3Key("ds1", 1))
+			if uploaded := err == nil; uploaded == test.wantErr {
+				t.Errorf("data uploaded = %v, want %v", uploaded, !test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/schema"
+	"downloader/pkg/job"
+	"downloader/util"
+	"fmt"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	DefaultPreviewRowLimit = 20
+	MaxPreviewRowLimit     = 500
+
+	// maximum number of error cells listed in the preview, all of them are counted
+	maxPreviewErrorCells = 100
+)
+
+type PreviewOptions struct {
+	// number of rows returned, DefaultPreviewRowLimit when 0, at most MaxPreviewRowLimit
+	RowLimit int `form:"rowLimit" json:"rowLimit"`
+}
+
+type PreviewDateColumn struct {
+	Name string `json:"name"`
+	// strftime format of the dates written as text, empty for date cells
+	Format string `json:"format,omitempty"`
+}
+
+type PreviewCellError struct {
+	Row    int    `json:"row"` // 1-based sheet row number
+	Column string `json:"column"`
+	Cell   string `json:"cell,omitempty"`
+	Kind   string `json:"kind"`
+}
+
+// Preview is the sheet as a sync would read it
+type Preview struct {
+	HeaderRow      int                 `json:"headerRow"`
+	Header         []string            `json:"header"`
+	Schema         schema.TableSchema  `json:"schema"`
+	DateColumns    []PreviewDateColumn `json:"dateColumns"`
+	ErrorCells     []PreviewCellError  `json:"errorCells"`
+	ErrorCellCount int                 `json:"errorCellCount"`
+	RowCount       int                 `json:"rowCount"`
+	// nil without quality rules, blocking rules do not fail the preview
+	Quality *QualityReport `json:"quality,omitempty"`
+	// first rows of the data, by column name, null for empty cells
+	Rows []map[string]*string `json:"rows"`
+}
+
+// Preview runs the stages & infers the schema like Run, without uploading anything.
+// The config must have no IdWriter so that nothing is written to the spreadsheet.
+func (p *Pipeline) Preview(ctx context.Context, filePath string, sheetName string, options PreviewOptions) (*Preview, error) {
+	if p.config.IdWriter != nil {
+		return nil, fmt.Errorf("Preview pipeline must not write ids")
+	}
+	rowLimit := options.RowLimit
+	if rowLimit <= 0 {
+		rowLimit = DefaultPreviewRowLimit
+	}
+	if rowLimit > MaxPreviewRowLimit {
+		rowLimit = MaxPreviewRowLimit
+	}
+
+	job.ReportStage(ctx, "load")
+	table, err := p.Load(filePath, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	header := table.Headers()
+	if err := p.Transform(ctx, table); err != nil {
+		return nil, err
+	}
+	job.ReportStage(ctx, "infer-schema")
+	preview := &Preview{
+		HeaderRow:   table.HeaderRow,
+		Header:      header,
+		Schema:      InferSchema(table),
+		DateColumns: make([]PreviewDateColumn, 0),
+		RowCount:    len(table.Rows),
+		Quality:     p.quality.Report,
+		Rows:        make([]map[string]*string, 0, rowLimit),
+	}
+	for _, col := range table.Columns {
+		if col.IsDate {
+			preview.DateColumns = append(preview.DateColumns, PreviewDateColumn{Name: col.Name, Format: col.DateFormat})
+		}
+	}
+	if preview.ErrorCells, preview.ErrorCellCount, err = previewErrorCells(table); err != nil {
+		return nil, err
+	}
+
+	errorsColIdx := table.ColumnIndex(CellErrorsColumnName)
+	for rowIdx, row := range table.Rows {
+		if rowIdx == rowLimit {
+			break
+		}
+		values := make(map[string]*string, len(row))
+		for idx := range row {
+			if idx == errorsColIdx {
+				continue
+			}
+			if row[idx].IsEmpty() {
+				values[table.Columns[idx].Name] = nil
+			} else {
+				values[table.Columns[idx].Name] = &row[idx].Value
+			}
+		}
+		preview.Rows = append(preview.Rows, values)
+	}
+	return preview, nil
+}
+
+// previewErrorCells reads the error cells back from the cell errors column, in sheet order
+func previewErrorCells(table *Table) ([]PreviewCellError, int, error) {
+	errorCells := make([]PreviewCellError, 0)
+	errorsColIdx := table.ColumnIndex(CellErrorsColumnName)
+	if errorsColIdx == -1 {
+		return errorCells, 0, nil
+	}
+	columnNames := make(map[string]string, len(table.Columns))
+	columnIndexes := make(map[string]int, len(table.Columns))
+	for idx, col := range table.Columns {
+		columnNames[util.HashFieldName(col.Name)] = col.Name
+		columnIndexes[col.Name] = idx
+	}
+
+	count := 0
+	for rowIdx, row := range table.Rows {
+		if row[errorsColIdx].IsEmpty() {
+			continue
+		}
+		var rowErrors map[string]CellError
+		if err := jsoniter.UnmarshalFromString(row[errorsColIdx].Value, &rowErrors); err != nil {
+			return nil, 0, fmt.Errorf("Error when unmarshalling cell errors: %w", err)
+		}
+		count += len(rowErrors)
+		rowErrorCells := make([]PreviewCellError, 0, len(rowErrors))
+		for field, cellError := range rowErrors {
+			rowErrorCells = append(rowErrorCells, PreviewCellError{
+				Row:    table.RowNumber(rowIdx),
+				Column: columnNames[field],
+				Cell:   cellError.Cell,
+				Kind:   cellError.Kind,
+			})
+		}
+		sort.Slice(rowErrorCells, func(i, j int) bool {
+			return columnIndexes[rowErrorCells[i].Column] < columnIndexes[rowErrorCells[j].Column]
+		})
+		for _, errorCell := range rowErrorCells {
+			if len(errorCells) < maxPreviewErrorCells {
+				errorCells = append(errorCells, errorCell)
+			}
+		}
+	}
+	return errorCells, count, nil
+}
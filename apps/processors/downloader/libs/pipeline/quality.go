@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/pkg/e"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// kinds of quality rules
+const (
+	RequiredRule      = "required"      // the cell is not empty
+	PatternRule       = "pattern"       // the whole text matches Pattern
+	RangeRule         = "range"         // the number is between Min & Max
+	AllowedValuesRule = "allowedValues" // the value is one of Values
+	UniqueRule        = "unique"        // no other row has the value
+)
+
+// maximum number of offending rows kept by rule in the quality report
+const maxQualitySampleRows = 20
+
+// QualityRule is a check of the cells of a column, the empty cells only fail the required rule
+type QualityRule struct {
+	Column  string   `json:"column"`
+	Kind    string   `json:"kind"`
+	Pattern string   `json:"pattern,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Values  []string `json:"values,omitempty"`
+	// a failed blocking rule fails the sync, the others are only reported
+	Blocking bool `json:"blocking"`
+}
+
+type QualityOptions struct {
+	QualityRules []QualityRule `form:"qualityRules" json:"qualityRules"`
+}
+
+type QualityRuleResult struct {
+	Rule           QualityRule `json:"rule"`
+	Passed         bool        `json:"passed"`
+	ViolationCount int         `json:"violationCount"`
+	// 1-based sheet row numbers of the first offending rows
+	SampleRows []int `json:"sampleRows"`
+}
+
+// QualityReport is the result of the quality rules of a sync version
+type QualityReport struct {
+	RowCount int                 `json:"rowCount"`
+	Passed   bool                `json:"passed"`
+	Rules    []QualityRuleResult `json:"rules"`
+}
+
+// BlockingFailures returns the failed blocking rules
+func (r *QualityReport) BlockingFailures() []QualityRuleResult {
+	failures := make([]QualityRuleResult, 0)
+	for _, result := range r.Rules {
+		if result.Rule.Blocking && !result.Passed {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// QualityStage evaluates the quality rules, the report is kept in Report.
+// It runs before the id column, so that a failed blocking rule stops the sync before any id is written to the sheet,
+// and before the errors are replaced, so that error cells count as empty cells.
+type QualityStage struct {
+	Options QualityOptions
+	// called with the report once the rules are evaluated, its error stops the pipeline
+	OnReport func(ctx context.Context, report *QualityReport) error
+
+	// result, nil without rules
+	Report *QualityReport
+}
+
+func (s *QualityStage) Name() string {
+	return "quality"
+}
+
+func (s *QualityStage) Apply(ctx context.Context, table *Table) error {
+	if len(s.Options.QualityRules) == 0 {
+		return nil
+	}
+	report := &QualityReport{RowCount: len(table.Rows), Passed: true, Rules: make([]QualityRuleResult, 0, len(s.Options.QualityRules))}
+	for _, rule := range s.Options.QualityRules {
+		check, err := rule.check()
+		if err != nil {
+			return e.NewExternalErrorWithDescription(e.QUALITY_RULES_INVALID, "Invalid quality rules", err.Error())
+		}
+		colIdx := table.ColumnIndex(SafeHeaderName(rule.Column))
+		if colIdx == -1 {
+			return e.NewExternalErrorWithDescription(e.QUALITY_RULES_INVALID, "Invalid quality rules", fmt.Sprintf("Column %s of %s rule not found in header", rule.Column, rule.Kind))
+		}
+
+		result := QualityRuleResult{Rule: rule, SampleRows: make([]int, 0)}
+		seen := make(map[string]bool)
+		for rowIdx, row := range table.Rows {
+			cell := row[colIdx]
+			empty := cell.IsEmpty() || cell.Type == ErrorCell
+			var ok bool
+			switch {
+			case rule.Kind == RequiredRule:
+				ok = !empty
+			case empty:
+				ok = true
+			case rule.Kind == UniqueRule:
+				ok = !seen[cell.Value]
+				seen[cell.Value] = true
+			default:
+				ok = check(cell.Value)
+			}
+			if ok {
+				continue
+			}
+			result.ViolationCount++
+			if len(result.SampleRows) < maxQualitySampleRows {
+				result.SampleRows = append(result.SampleRows, table.RowNumber(rowIdx))
+			}
+		}
+		result.Passed = result.ViolationCount == 0
+		report.Passed = report.Passed && result.Passed
+		report.Rules = append(report.Rules, result)
+	}
+	s.Report = report
+	if s.OnReport != nil {
+		return s.OnReport(ctx, report)
+	}
+	return nil
+}
+
+// check returns the check of the value of a non empty cell, the required & unique rules are checked by the stage
+func (r QualityRule) check() (func(value string) bool, error) {
+	switch r.Kind {
+	case RequiredRule, UniqueRule:
+		return nil, nil
+	case PatternRule:
+		pattern, err := regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of column %s: %w", r.Column, err)
+		}
+		return pattern.MatchString, nil
+	case RangeRule:
+		if r.Min == nil && r.Max == nil {
+			return nil, fmt.Errorf("range rule of column %s has no min nor max", r.Column)
+		}
+		return func(value string) bool {
+			number, err := strconv.ParseFloat(value, 64)
+			return err == nil && (r.Min == nil || number >= *r.Min) && (r.Max == nil || number <= *r.Max)
+		}, nil
+	case AllowedValuesRule:
+		if len(r.Values) == 0 {
+			return nil, fmt.Errorf("allowed values rule of column %s has no values", r.Column)
+		}
+		allowed := make(map[string]bool, len(r.Values))
+		for _, value := range r.Values {
+			allowed[value] = true
+		}
+		return func(value string) bool {
+			return allowed[value]
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule kind %s, expected one of %s", r.Kind, strings.Join([]string{RequiredRule, PatternRule, RangeRule, AllowedValuesRule, UniqueRule}, ", "))
+	}
+}
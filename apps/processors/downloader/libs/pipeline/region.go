@@ -0,0 +1,191 @@
+package pipeline
+
+import (
+	"downloader/pkg/e"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+// Region selects the data of a sheet, the header is the first row of the region.
+// At most one of the fields is set, the whole sheet is used when none is.
+type Region struct {
+	Range      string // A1 range, rows or columns may be open, e.g. A3:F200, A3:F, B:F
+	Table      string // Excel table
+	NamedRange string // defined name of the workbook, Google named ranges are exported as defined names
+}
+
+func (r Region) IsWholeSheet() bool {
+	return r.Range == "" && r.Table == "" && r.NamedRange == ""
+}
+
+// bounds of a region in 1-based sheet coordinates, 0 for an open side
+type bounds struct {
+	firstRow, firstCol int
+	lastRow, lastCol   int
+}
+
+var (
+	wholeSheetBounds = bounds{firstRow: 1, firstCol: 1}
+	a1RefRegex       = regexp.MustCompile(`^([A-Z]{0,3})(\d*)$`)
+)
+
+// parseA1Range parses a range like A3:F200, the sheet name is ignored
+func parseA1Range(ref string) (bounds, error) {
+	if idx := strings.LastIndex(ref, "!"); idx != -1 {
+		ref = ref[idx+1:]
+	}
+	ref = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(ref), "$", ""))
+	parts := strings.Split(ref, ":")
+	if ref == "" || len(parts) > 2 {
+		return bounds{}, fmt.Errorf("Invalid range %s", ref)
+	}
+
+	first, err := parseA1Ref(parts[0])
+	if err != nil {
+		return bounds{}, err
+	}
+	result := bounds{firstRow: first.row, firstCol: first.col}
+	if result.firstRow == 0 {
+		result.firstRow = 1
+	}
+	if result.firstCol == 0 {
+		result.firstCol = 1
+	}
+	if len(parts) == 2 {
+		last, err := parseA1Ref(parts[1])
+		if err != nil {
+			return bounds{}, err
+		}
+		result.lastRow, result.lastCol = last.row, last.col
+	}
+	if (result.lastRow != 0 && result.lastRow < result.firstRow) || (result.lastCol != 0 && result.lastCol < result.firstCol) {
+		return bounds{}, fmt.Errorf("Invalid range %s, the end is before the start", ref)
+	}
+	return result, nil
+}
+
+type a1Ref struct {
+	row, col int
+}
+
+func parseA1Ref(ref string) (a1Ref, error) {
+	match := a1RefRegex.FindStringSubmatch(ref)
+	if match == nil || ref == "" {
+		return a1Ref{}, fmt.Errorf("Invalid cell reference %s", ref)
+	}
+	var result a1Ref
+	var err error
+	if match[1] != "" {
+		if result.col, err = excelize.ColumnNameToNumber(match[1]); err != nil {
+			return a1Ref{}, err
+		}
+	}
+	if match[2] != "" {
+		if result.row, err = strconv.Atoi(match[2]); err != nil || result.row == 0 {
+			return a1Ref{}, fmt.Errorf("Invalid row in cell reference %s", ref)
+		}
+	}
+	return result, nil
+}
+
+// resolveRegion returns the bounds of the region in the sheet
+func resolveRegion(file *excelize.File, sheetName string, region Region) (bounds, error) {
+	setCount := 0
+	for _, field := range []string{region.Range, region.Table, region.NamedRange} {
+		if field != "" {
+			setCount++
+		}
+	}
+	if setCount > 1 {
+		return bounds{}, e.NewExternalErrorWithDescription(e.REGION_INVALID, "Invalid data region", "Only one of range, table or named range can be set")
+	}
+
+	switch {
+	case region.Range != "":
+		result, err := parseA1Range(region.Range)
+		if err != nil {
+			return bounds{}, e.WrapExternalError(err, e.REGION_INVALID, "Invalid data region")
+		}
+		return result, nil
+	case region.Table != "":
+		return resolveTable(file, sheetName, region.Table)
+	case region.NamedRange != "":
+		return resolveDefinedName(file, sheetName, region.NamedRange)
+	default:
+		return wholeSheetBounds, nil
+	}
+}
+
+func resolveTable(file *excelize.File, sheetName string, tableName string) (bounds, error) {
+	tables, err := file.GetTables(sheetName)
+	if err != nil {
+		return bounds{}, fmt.Errorf("Error when reading tables of sheet %s: %w", sheetName, err)
+	}
+	for _, table := range tables {
+		if !strings.EqualFold(table.Name, tableName) {
+			continue
+		}
+		if table.ShowHeaderRow != nil && !*table.ShowHeaderRow {
+			return bounds{}, e.NewExternalErrorWithDescription(e.REGION_INVALID, "Invalid data region", fmt.Sprintf("Table %s has no header row", tableName))
+		}
+		result, err := parseA1Range(table.Range)
+		if err != nil {
+			return bounds{}, e.WrapExternalError(err, e.REGION_INVALID, "Invalid data region")
+		}
+		// the totals row is not data
+		result.lastRow -= tableTotalsRowCount(file, table.Name)
+		return result, nil
+	}
+	return bounds{}, e.NewExternalErrorWithDescription(e.REGION_NOT_FOUND, "Data region not found", fmt.Sprintf("Table %s not found in sheet %s", tableName, sheetName))
+}
+
+// tableTotalsRowCount reads the totals row count from the table part, excelize does not expose it
+func tableTotalsRowCount(file *excelize.File, tableName string) int {
+	count := 0
+	file.Pkg.Range(func(key, value interface{}) bool {
+		path, ok := key.(string)
+		content, isBytes := value.([]byte)
+		if !ok || !isBytes || !strings.HasPrefix(path, "xl/tables/") {
+			return true
+		}
+		var table struct {
+			Name           string `xml:"name,attr"`
+			TotalsRowCount int    `xml:"totalsRowCount,attr"`
+		}
+		if err := xml.Unmarshal(content, &table); err != nil || table.Name != tableName {
+			return true
+		}
+		count = table.TotalsRowCount
+		return false
+	})
+	return count
+}
+
+func resolveDefinedName(file *excelize.File, sheetName string, name string) (bounds, error) {
+	for _, definedName := range file.GetDefinedName() {
+		if !strings.EqualFold(definedName.Name, name) || (definedName.Scope != "Workbook" && definedName.Scope != sheetName) {
+			continue
+		}
+		refersTo := strings.TrimPrefix(definedName.RefersTo, "=")
+		if strings.Contains(refersTo, ",") {
+			return bounds{}, e.NewExternalErrorWithDescription(e.REGION_INVALID, "Invalid data region", fmt.Sprintf("Named range %s has several areas", name))
+		}
+		if idx := strings.LastIndex(refersTo, "!"); idx != -1 {
+			refSheet := strings.ReplaceAll(strings.Trim(refersTo[:idx], "'"), "''", "'")
+			if refSheet != sheetName {
+				return bounds{}, e.NewExternalErrorWithDescription(e.REGION_NOT_FOUND, "Data region not found", fmt.Sprintf("Named range %s is in sheet %s, not in sheet %s", name, refSheet, sheetName))
+			}
+		}
+		result, err := parseA1Range(refersTo)
+		if err != nil {
+			return bounds{}, e.WrapExternalError(err, e.REGION_INVALID, "Invalid data region")
+		}
+		return result, nil
+	}
+	return bounds{}, e.NewExternalErrorWithDescription(e.REGION_NOT_FOUND, "Data region not found", fmt.Sprintf("Named range %s not found", name))
+}
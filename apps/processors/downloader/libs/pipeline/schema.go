@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"downloader/libs/inference"
+	"downloader/libs/schema"
+	"downloader/util"
+	"fmt"
+	"strconv"
+)
+
+// InferSchema detects the schema of the table, keyed by hashed field name.
+// The text booleans of boolean columns, e.g. yes & no, are written as true & false.
+func InferSchema(table *Table) schema.TableSchema {
+	tableSchema := make(schema.TableSchema, len(table.Columns))
+	for colIdx, col := range table.Columns {
+		hashedFieldName := util.HashFieldName(col.Name)
+		if hashedFieldName == schema.HashedPrimaryField {
+			tableSchema[hashedFieldName] = inference.PrimaryFieldSchema()
+			continue
+		}
+		if hashedFieldName == schema.HashedCellMetaField {
+			tableSchema[hashedFieldName] = inference.CellMetaFieldSchema()
+			continue
+		}
+		if hashedFieldName == schema.HashedCellErrorsField {
+			tableSchema[hashedFieldName] = inference.CellErrorsFieldSchema()
+			continue
+		}
+		tableSchema[hashedFieldName] = inferFieldSchema(table, colIdx)
+	}
+	return tableSchema
+}
+
+func inferFieldSchema(table *Table, colIdx int) schema.FieldSchema {
+	inferrer := inference.NewColumnInferrer(inference.Options{})
+	isDate := table.Columns[colIdx].IsDate
+	for _, row := range table.Rows {
+		inferrer.Add(classifyCell(row[colIdx], isDate))
+	}
+	result := inferrer.Result()
+	if result.Type == schema.Boolean {
+		normalizeBooleans(table, colIdx)
+	}
+	if format := table.Columns[colIdx].DateFormat; format != "" && (result.Type == schema.Date || result.Type == schema.DateTime) {
+		result.Format = format
+	}
+	return result.FieldSchema(table.Columns[colIdx].Name)
+}
+
+// classifyCell uses the type of the xlsx cell, only text cells are parsed
+func classifyCell(cell Cell, isDateColumn bool) inference.Value {
+	switch cell.Type {
+	case EmptyCell:
+		return inference.Value{Kind: inference.KindEmpty}
+	case NumberCell:
+		switch cell.NumberFormat.Kind {
+		case CurrencyNumber:
+			return inference.Value{Kind: inference.KindCurrency, Text: cell.Value, Unit: cell.NumberFormat.Unit}
+		case PercentNumber:
+			return inference.Value{Kind: inference.KindPercent, Text: cell.Value, Unit: cell.NumberFormat.Unit}
+		case DurationNumber:
+			return inference.Value{Kind: inference.KindDuration, Text: cell.Value, Unit: cell.NumberFormat.Unit}
+		}
+		return inference.ClassifyNumber(cell.Value)
+	case BooleanCell:
+		return inference.Value{Kind: inference.KindBoolean, Text: cell.Value, BooleanFormat: inference.BooleanCheckbox, BooleanValue: cell.Value == "true"}
+	case DateCell:
+		// date cells of a mixed column keep their display format
+		if !isDateColumn {
+			return inference.Value{Kind: inference.KindString, Text: cell.Value}
+		}
+		var serialNumber float64
+		if _, err := fmt.Sscanf(cell.Raw, "%g", &serialNumber); err != nil {
+			return inference.Value{Kind: inference.KindString, Text: cell.Value}
+		}
+		return inference.ClassifySerialDate(cell.Value, serialNumber)
+	case StringCell:
+		return inference.ClassifyString(cell.Value)
+	default:
+		// error cells are written as null, the error is kept in the cell errors column
+		return inference.Value{Kind: inference.KindEmpty}
+	}
+}
+
+// normalizeBooleans writes the text booleans of the column as true & false
+func normalizeBooleans(table *Table, colIdx int) {
+	for _, row := range table.Rows {
+		cell := row[colIdx]
+		if cell.Type != StringCell {
+			continue
+		}
+		if value := inference.ClassifyString(cell.Value); value.Kind == inference.KindBoolean {
+			row[colIdx] = Cell{Type: BooleanCell, Value: strconv.FormatBool(value.BooleanValue), Raw: cell.Raw}
+		}
+	}
+}
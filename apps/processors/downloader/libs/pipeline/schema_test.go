@@ -0,0 +1,46 @@
+package pipeline
+
+import (
+	"downloader/libs/schema"
+	"downloader/util"
+	"testing"
+)
+
+func TestInferSchemaBooleans(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []string
+		wantType   schema.DataType
+		wantFormat string
+		want       []string
+	}{
+		{name: "yes & no", values: []string{"yes", "No", ""}, wantType: schema.Boolean, wantFormat: "yes/no", want: []string{"true", "false", ""}},
+		{name: "y & n", values: []string{"y", "n"}, wantType: schema.Boolean, wantFormat: "y/n", want: []string{"true", "false"}},
+		{name: "on & off", values: []string{"ON", "off"}, wantType: schema.Boolean, wantFormat: "on/off", want: []string{"true", "false"}},
+		{name: "true & false", values: []string{"TRUE", "False"}, wantType: schema.Boolean, wantFormat: "true/false", want: []string{"true", "false"}},
+		{name: "a single word", values: []string{"yes", "yes"}, wantType: schema.String, want: []string{"yes", "yes"}},
+		{name: "texts", values: []string{"yes", "maybe"}, wantType: schema.String, want: []string{"yes", "maybe"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table := &Table{HeaderRow: 1, Columns: []Column{{Name: "value", SourceIndex: 1}}}
+			for _, value := range test.values {
+				cell := Cell{Type: StringCell, Value: value, Raw: value}
+				if value == "" {
+					cell = Cell{Type: EmptyCell}
+				}
+				table.Rows = append(table.Rows, []Cell{cell})
+			}
+
+			field := InferSchema(table)[util.HashFieldName("value")]
+			if field.Type != test.wantType || field.Format != test.wantFormat {
+				t.Errorf("got type %s with format %q, want %s with %q", field.Type, field.Format, test.wantType, test.wantFormat)
+			}
+			for idx, want := range test.want {
+				if got := table.Rows[idx][0].Value; got != want {
+					t.Errorf("row %d: got %q, want %q", idx, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/libs/schema"
+	"downloader/pkg/e"
+	"downloader/util"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	excelize "github.com/xuri/excelize/v2"
+)
+
+const (
+	IdColumnName         = schema.OriginalPrimaryFieldName
+	CellErrorsColumnName = schema.OriginalCellErrorsFieldName
+)
+
+// Stage is one step of the ingest pipeline, it transforms the table in place
+type Stage interface {
+	Name() string
+	Apply(ctx context.Context, table *Table) error
+}
+
+// #########################################################################################################
+
+// ValidateHeaderStage rejects sheets without header and sheets with duplicated id column
+type ValidateHeaderStage struct {
+	SheetEmptyCode int
+}
+
+func (s *ValidateHeaderStage) Name() string {
+	return "validate-header"
+}
+
+func (s *ValidateHeaderStage) Apply(ctx context.Context, table *Table) error {
+	if len(table.Columns) == 0 {
+		return e.NewExternalErrorWithDescription(s.SheetEmptyCode, "Sheet is empty or missing header row", "Header row is empty")
+	}
+	idColCount := 0
+	for _, col := range table.Columns {
+		if col.Name == IdColumnName {
+			idColCount++
+		}
+	}
+	if idColCount > 1 {
+		return e.NewExternalErrorWithDescription(e.ID_COL_DUPLICATED, fmt.Sprintf("The id column (%s) is duplicated", IdColumnName), fmt.Sprintf("Found %d id columns", idColCount))
+	}
+	return nil
+}
+
+// #########################################################################################################
+
+// TrimGhostCellsStage removes the empty rows left at the end of the used range
+type TrimGhostCellsStage struct{}
+
+func (s *TrimGhostCellsStage) Name() string {
+	return "trim-ghost-cells"
+}
+
+func (s *TrimGhostCellsStage) Apply(ctx context.Context, table *Table) error {
+	last := len(table.Rows)
+	for last > 0 && isEmptyRow(table.Rows[last-1]) {
+		last--
+	}
+	table.Rows = table.Rows[:last]
+	return nil
+}
+
+func isEmptyRow(row []Cell) bool {
+	for _, cell := range row {
+		if !cell.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// #########################################################################################################
+
+// TrimFieldsStage trims spaces of headers & values
+type TrimFieldsStage struct{}
+
+func (s *TrimFieldsStage) Name() string {
+	return "trim-fields"
+}
+
+func (s *TrimFieldsStage) Apply(ctx context.Context, table *Table) error {
+	for idx := range table.Columns {
+		table.Columns[idx].Name = strings.TrimSpace(table.Columns[idx].Name)
+	}
+	for _, row := range table.Rows {
+		for idx, cell := range row {
+			if cell.Type != StringCell {
+				continue
+			}
+			row[idx].Value = strings.TrimSpace(cell.Value)
+			if row[idx].Value == "" {
+				row[idx] = Cell{Type: EmptyCell}
+			}
+		}
+	}
+	return nil
+}
+
+// #########################################################################################################
+
+// NormalizeDateStage converts columns holding only date cells to ISO dates in UTC,
+// the dates written as text are read with the date options & their format is kept in the column
+type NormalizeDateStage struct {
+	Timezone string
+	Options  DateOptions
+}
+
+const nanosInADay = float64((24 * time.Hour) / time.Nanosecond)
+
+func (s *NormalizeDateStage) Name() string {
+	return "normalize-date"
+}
+
+func (s *NormalizeDateStage) Apply(ctx context.Context, table *Table) error {
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return e.WrapExternalError(err, e.INVALID_TIMEZONE, fmt.Sprintf("Invalid timezone %s", timezone))
+	}
+	settings, err := s.Options.settings()
+	if err != nil {
+		return err
+	}
+
+	for colIdx := range table.Columns {
+		textDates, format := settings.parseTextDates(table, colIdx, location)
+		if textDates == nil && !isDateColumn(table, colIdx) {
+			continue
+		}
+		table.Columns[colIdx].IsDate = true
+		table.Columns[colIdx].DateFormat = format
+		for rowIdx, row := range table.Rows {
+			if date, ok := textDates[rowIdx]; ok {
+				row[colIdx] = textDateCell(date)
+			} else if cell := row[colIdx]; cell.Type == DateCell {
+				row[colIdx] = convertSerialNumberCell(cell, location)
+			}
+		}
+	}
+	return nil
+}
+
+func isDateColumn(table *Table, colIdx int) bool {
+	hasDate := false
+	for _, row := range table.Rows {
+		switch row[colIdx].Type {
+		case DateCell:
+			hasDate = true
+		case EmptyCell, ErrorCell:
+		default:
+			return false
+		}
+	}
+	return hasDate
+}
+
+func convertSerialNumberCell(cell Cell, location *time.Location) Cell {
+	var serialNumber float64
+	if _, err := fmt.Sscanf(cell.Raw, "%g", &serialNumber); err != nil {
+		return Cell{Type: ErrorCell, Value: cell.Value, Raw: cell.Raw}
+	}
+	if serialNumber == 0 {
+		return Cell{Type: EmptyCell}
+	}
+	if serialNumber < 1 {
+		return Cell{Type: DateCell, Value: ConvertSerialNumberToTime(serialNumber), Raw: cell.Raw}
+	}
+	return Cell{Type: DateCell, Value: ConvertSerialNumberToDate(serialNumber, location), Raw: cell.Raw}
+}
+
+// ConvertSerialNumberToTime converts the serial number of a time of day, it is not shifted by the timezone
+func ConvertSerialNumberToTime(serialNumber float64) string {
+	offset := time.Duration(math.Round(serialNumber*nanosInADay/float64(time.Millisecond))) * time.Millisecond
+	return time.Time{}.Add(offset).Format("15:04:05.999")
+}
+
+func ConvertSerialNumberToDate(serialNumber float64, location *time.Location) string {
+	anchorTime := time.Date(1899, time.December, 30, 0, 0, 0, 0, location)
+	offsetFractionsNs := serialNumber*nanosInADay - float64(int64(serialNumber))*nanosInADay
+
+	return anchorTime.
+		AddDate(0, 0, int(serialNumber)).
+		Add(time.Duration(offsetFractionsNs)).
+		UTC().
+		Format("2006-01-02T15:04:05.999Z")
+}
+
+// #########################################################################################################
+
+// SafeHeaderStage normalizes header names, removes columns without header & deduplicates names
+type SafeHeaderStage struct{}
+
+var (
+	headerSpaceRegex    = regexp.MustCompile(`\s+`)
+	headerRemovingRegex = regexp.MustCompile(`["',;]`)
+)
+
+func (s *SafeHeaderStage) Name() string {
+	return "safe-header"
+}
+
+func (s *SafeHeaderStage) Apply(ctx context.Context, table *Table) error {
+	selecting := make([]int, 0, len(table.Columns))
+	for idx := range table.Columns {
+		name := SafeHeaderName(table.Columns[idx].Name)
+		table.Columns[idx].Name = name
+		if name != "" {
+			selecting = append(selecting, idx)
+		}
+	}
+	table.SelectColumns(selecting)
+
+	existed := make(map[string]bool)
+	for idx, col := range table.Columns {
+		name := col.Name
+		for dedupeNumber := 1; existed[name]; dedupeNumber++ {
+			name = fmt.Sprintf("%s (%d)", col.Name, dedupeNumber)
+		}
+		existed[name] = true
+		table.Columns[idx].Name = name
+	}
+	return nil
+}
+
+func SafeHeaderName(name string) string {
+	return headerRemovingRegex.ReplaceAllString(
+		headerSpaceRegex.ReplaceAllString(strings.TrimSpace(name), " "),
+		"",
+	)
+}
+
+// #########################################################################################################
+
+// ReplaceErrorStage replaces spreadsheet error values (#N/A, #REF!...) with nulls,
+// the error kind & the cell reference are kept in the cell errors column, keyed by hashed field name.
+// The cell errors column is the error mask of the row, a null value is not an error unless it is in the mask.
+type ReplaceErrorStage struct{}
+
+// CellError is the spreadsheet error of a cell replaced by null
+type CellError struct {
+	Kind string `json:"kind"`
+	Cell string `json:"cell,omitempty"`
+}
+
+func (s *ReplaceErrorStage) Name() string {
+	return "replace-error"
+}
+
+func (s *ReplaceErrorStage) Apply(ctx context.Context, table *Table) error {
+	idColIdx := table.ColumnIndex(IdColumnName)
+	rowErrors := make(map[int]map[string]CellError)
+	for rowIdx, row := range table.Rows {
+		for idx, cell := range row {
+			if idx == idColIdx || cell.Type != ErrorCell {
+				continue
+			}
+			row[idx] = Cell{Type: EmptyCell}
+
+			cellError := CellError{Kind: cellErrorKind(cell)}
+			if col := table.Columns[idx]; col.SourceIndex != 0 {
+				cellError.Cell, _ = excelize.CoordinatesToCellName(col.SourceIndex, table.RowNumber(rowIdx))
+			}
+			if rowErrors[rowIdx] == nil {
+				rowErrors[rowIdx] = make(map[string]CellError)
+			}
+			rowErrors[rowIdx][util.HashFieldName(table.Columns[idx].Name)] = cellError
+		}
+	}
+	if len(rowErrors) == 0 {
+		return nil
+	}
+
+	table.AppendColumn(Column{Name: CellErrorsColumnName})
+	errorsColIdx := len(table.Columns) - 1
+	for rowIdx, errors := range rowErrors {
+		value, err := jsoniter.MarshalToString(errors)
+		if err != nil {
+			return fmt.Errorf("Error when marshalling cell errors: %w", err)
+		}
+		table.Rows[rowIdx][errorsColIdx] = Cell{Type: StringCell, Value: value, Raw: value}
+	}
+	return nil
+}
+
+// cellErrorKind is the error value of the cell, date cells that could not be converted are #VALUE!
+func cellErrorKind(cell Cell) string {
+	if errorValueRegex.MatchString(cell.Raw) {
+		return cell.Raw
+	}
+	return "#VALUE!"
+}
@@ -0,0 +1,283 @@
+package pipeline
+
+import (
+	"context"
+	"downloader/pkg/e"
+	"downloader/util"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	excelize "github.com/xuri/excelize/v2"
+)
+
+func TestNormalizeDateStage(t *testing.T) {
+	date := time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)
+	setTexts := func(texts ...string) func(f *excelize.File) {
+		return func(f *excelize.File) {
+			for idx, text := range texts {
+				cell, _ := excelize.CoordinatesToCellName(1, idx+2)
+				f.SetCellStr(fixtureSheet, cell, text)
+			}
+		}
+	}
+	tests := []struct {
+		name       string
+		set        func(f *excelize.File)
+		timezone   string
+		options    DateOptions
+		wantDate   bool
+		wantFormat string
+		want       []string
+	}{
+		{
+			name:     "date cells in UTC",
+			set:      func(f *excelize.File) { f.SetCellValue(fixtureSheet, "A2", date) },
+			wantDate: true,
+			want:     []string{"2023-03-15T00:00:00Z"},
+		},
+		{
+			name:     "date cells in timezone",
+			set:      func(f *excelize.File) { f.SetCellValue(fixtureSheet, "A2", date) },
+			timezone: "Asia/Ho_Chi_Minh",
+			wantDate: true,
+			want:     []string{"2023-03-14T17:00:00Z"},
+		},
+		{
+			name:       "text dates with detected order",
+			set:        setTexts("15/03/2023", "01/02/2023"),
+			wantDate:   true,
+			wantFormat: "%d/%m/%Y",
+			want:       []string{"2023-03-15T00:00:00Z", "2023-02-01T00:00:00Z"},
+		},
+		{
+			name:       "text dates with date order option",
+			set:        setTexts("03/04/2023"),
+			options:    DateOptions{DateOrder: "MDY"},
+			wantDate:   true,
+			wantFormat: "%m/%d/%Y",
+			want:       []string{"2023-03-04T00:00:00Z"},
+		},
+		{
+			name:       "text dates with column format",
+			set:        setTexts("2023.15.03"),
+			options:    DateOptions{DateFormats: []string{"value=%Y.%d.%m"}},
+			wantDate:   true,
+			wantFormat: "%Y.%d.%m",
+			want:       []string{"2023-03-15T00:00:00Z"},
+		},
+		{
+			name: "texts & numbers",
+			set: func(f *excelize.File) {
+				f.SetCellStr(fixtureSheet, "A2", "15/03/2023")
+				f.SetCellValue(fixtureSheet, "A3", 12)
+			},
+			want: []string{"15/03/2023", "12"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table := readFixture(t, writeFixture(t, []string{"value"}, test.set))
+			stage := &NormalizeDateStage{Timezone: test.timezone, Options: test.options}
+			if err := stage.Apply(context.Background(), table); err != nil {
+				t.Fatal(err)
+			}
+			if col := table.Columns[0]; col.IsDate != test.wantDate || col.DateFormat != test.wantFormat {
+				t.Errorf("got column date %v with format %q, want %v with %q", col.IsDate, col.DateFormat, test.wantDate, test.wantFormat)
+			}
+			if len(table.Rows) != len(test.want) {
+				t.Fatalf("got %d rows, want %d", len(table.Rows), len(test.want))
+			}
+			for idx, want := range test.want {
+				if got := table.Rows[idx][0].Value; got != want {
+					t.Errorf("row %d: got %q, want %q", idx, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		table := readFixture(t, writeFixture(t, []string{"value"}, nil))
+		err := (&NormalizeDateStage{Timezone: "Mars/Olympus"}).Apply(context.Background(), table)
+		if err == nil {
+			t.Fatal("got no error for an invalid timezone")
+		}
+	})
+}
+
+func TestReplaceErrorStage(t *testing.T) {
+	tests := []struct {
+		name       string
+		table      func(t *testing.T) *Table
+		wantErrors map[int]map[string]CellError // by row index, nil when the table has no cell errors column
+		wantCells  [][]string
+	}{
+		{
+			name: "no error",
+			table: func(t *testing.T) *Table {
+				return readFixture(t, writeFixture(t, []string{"a", "b"}, func(f *excelize.File) {
+					f.SetCellValue(fixtureSheet, "A2", 1)
+					f.SetCellStr(fixtureSheet, "B2", "#N/A")
+				}))
+			},
+			wantCells: [][]string{{"1", "#N/A"}},
+		},
+		{
+			name: "error cells",
+			table: func(t *testing.T) *Table {
+				filePath := writeFixture(t, []string{"a", "b"}, func(f *excelize.File) {
+					f.SetCellValue(fixtureSheet, "A2", 1)
+					f.SetCellStr(fixtureSheet, "B2", "")
+					f.SetCellValue(fixtureSheet, "A3", 2)
+				})
+				setRawCells(t, filePath, map[string]rawCell{"B2": {Type: "e", Value: "#REF!"}})
+				return readFixture(t, filePath)
+			},
+			wantErrors: map[int]map[string]CellError{
+				0: {util.HashFieldName("b"): {Kind: "#REF!", Cell: "B2"}},
+			},
+			wantCells: [][]string{{"1", ""}, {"2", ""}},
+		},
+		{
+			name: "unconverted value of generated column",
+			table: func(t *testing.T) *Table {
+				return &Table{
+					HeaderRow: 1,
+					Columns:   []Column{{Name: "a"}},
+					Rows:      [][]Cell{{{Type: ErrorCell, Value: "-1", Raw: "-1"}}},
+				}
+			},
+			wantErrors: map[int]map[string]CellError{
+				0: {util.HashFieldName("a"): {Kind: "#VALUE!"}},
+			},
+			wantCells: [][]string{{""}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table := test.table(t)
+			if err := (&ReplaceErrorStage{}).Apply(context.Background(), table); err != nil {
+				t.Fatal(err)
+			}
+			errorsColIdx := table.ColumnIndex(CellErrorsColumnName)
+			if (errorsColIdx != -1) != (test.wantErrors != nil) {
+				t.Fatalf("got cell errors column at %d, want column %v", errorsColIdx, test.wantErrors != nil)
+			}
+			for rowIdx, row := range table.Rows {
+				for colIdx, want := range test.wantCells[rowIdx] {
+					if got := row[colIdx].Value; got != want {
+						t.Errorf("row %d column %d: got %q, want %q", rowIdx, colIdx, got, want)
+					}
+				}
+				if errorsColIdx == -1 {
+					continue
+				}
+				cell := row[errorsColIdx]
+				wantErrors, ok := test.wantErrors[rowIdx]
+				if !ok {
+					if !cell.IsEmpty() {
+						t.Errorf("row %d: got cell errors %s, want none", rowIdx, cell.Value)
+					}
+					continue
+				}
+				var gotErrors map[string]CellError
+				if err := jsoniter.UnmarshalFromString(cell.Value, &gotErrors); err != nil {
+					t.Fatal(err)
+				}
+				if len(gotErrors) != len(wantErrors) {
+					t.Fatalf("row %d: got cell errors %v, want %v", rowIdx, gotErrors, wantErrors)
+				}
+				for key, want := range wantErrors {
+					if got := gotErrors[key]; got != want {
+						t.Errorf("row %d: got cell error %+v, want %+v", rowIdx, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQualityStage(t *testing.T) {
+	text := func(value string) Cell {
+		if value == "" {
+			return Cell{Type: EmptyCell}
+		}
+		return Cell{Type: StringCell, Value: value, Raw: value}
+	}
+	// sheet rows 2 to 6
+	table := func() *Table {
+		return &Table{
+			HeaderRow: 1,
+			Columns:   []Column{{Name: "Code"}, {Name: "Age"}, {Name: "Status"}},
+			Rows: [][]Cell{
+				{text("AB12"), text("30"), text("open")},
+				{text("AB12"), text("-1"), text("closed")},
+				{text("xAB34x"), text(""), text("Open")},
+				{{Type: ErrorCell, Value: "#N/A"}, text("120"), text("")},
+				{text("CD56"), text("abc"), text("open")},
+			},
+		}
+	}
+	min, max := 0.0, 100.0
+	tests := []struct {
+		name       string
+		rule       QualityRule
+		wantPassed bool
+		wantRows   []int
+	}{
+		{name: "required", rule: QualityRule{Column: "Age", Kind: RequiredRule}, wantRows: []int{4}},
+		{name: "required with error cell", rule: QualityRule{Column: "Code", Kind: RequiredRule}, wantRows: []int{5}},
+		{name: "unique", rule: QualityRule{Column: "Code", Kind: UniqueRule}, wantRows: []int{3}},
+		{name: "range", rule: QualityRule{Column: "Age", Kind: RangeRule, Min: &min, Max: &max}, wantRows: []int{3, 5, 6}},
+		{name: "range with min only", rule: QualityRule{Column: "Age", Kind: RangeRule, Min: &min}, wantRows: []int{3, 6}},
+		{name: "allowed values", rule: QualityRule{Column: "Status", Kind: AllowedValuesRule, Values: []string{"open", "closed"}}, wantRows: []int{4}},
+		{name: "pattern matches the whole text", rule: QualityRule{Column: "Code", Kind: PatternRule, Pattern: `[A-Z]{2}\d{2}`}, wantRows: []int{4}},
+		{name: "pattern with alternatives", rule: QualityRule{Column: "Status", Kind: PatternRule, Pattern: `open|closed`}, wantRows: []int{4}},
+		{name: "passed", rule: QualityRule{Column: "Status", Kind: PatternRule, Pattern: `(?i)open|closed`}, wantPassed: true, wantRows: []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stage := &QualityStage{Options: QualityOptions{QualityRules: []QualityRule{test.rule}}}
+			if err := stage.Apply(context.Background(), table()); err != nil {
+				t.Fatal(err)
+			}
+			report := stage.Report
+			if report == nil || len(report.Rules) != 1 {
+				t.Fatalf("got report %+v, want one rule", report)
+			}
+			result := report.Rules[0]
+			if result.Passed != test.wantPassed || report.Passed != test.wantPassed {
+				t.Errorf("got passed %v, want %v", result.Passed, test.wantPassed)
+			}
+			if result.ViolationCount != len(test.wantRows) || !reflect.DeepEqual(result.SampleRows, test.wantRows) {
+				t.Errorf("got %d violations on rows %v, want rows %v", result.ViolationCount, result.SampleRows, test.wantRows)
+			}
+		})
+	}
+
+	t.Run("no rules", func(t *testing.T) {
+		stage := &QualityStage{}
+		if err := stage.Apply(context.Background(), table()); err != nil || stage.Report != nil {
+			t.Errorf("got report %+v & error %v, want neither", stage.Report, err)
+		}
+	})
+
+	invalidRules := []QualityRule{
+		{Column: "Code", Kind: "checksum"},
+		{Column: "Code", Kind: PatternRule, Pattern: "[A-Z"},
+		{Column: "Age", Kind: RangeRule},
+		{Column: "Status", Kind: AllowedValuesRule},
+		{Column: "Missing", Kind: RequiredRule},
+	}
+	for _, rule := range invalidRules {
+		t.Run("invalid "+rule.Kind+" rule of "+rule.Column, func(t *testing.T) {
+			err := (&QualityStage{Options: QualityOptions{QualityRules: []QualityRule{rule}}}).Apply(context.Background(), table())
+			var externalError *e.ExternalError
+			if !errors.As(err, &externalError) || externalError.Code != e.QUALITY_RULES_INVALID {
+				t.Errorf("got error %v, want QUALITY_RULES_INVALID", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package pipeline
+
+type CellType int
+
+const (
+	EmptyCell CellType = iota
+	StringCell
+	NumberCell
+	BooleanCell
+	DateCell
+	ErrorCell
+)
+
+type Cell struct {
+	Type  CellType
+	Value string // value written to the snapshot
+	Raw   string // raw xlsx value, e.g. the serial number of a date cell
+
+	NumberFormat NumberFormat // meaning of a number cell given by its format
+}
+
+func (c Cell) IsEmpty() bool {
+	return c.Type == EmptyCell
+}
+
+type Column struct {
+	Name        string
+	SourceIndex int // 1-based column index in the sheet, 0 for generated columns
+	IsDate      bool
+	DateFormat  string // strftime format of the dates written as text
+}
+
+// Table is the in-memory representation of a sheet that every stage works on.
+// Rows[i][j] is the cell of Columns[j] in sheet row RowNumber(i).
+type Table struct {
+	HeaderRow int // 1-based sheet row number of the header
+	Columns   []Column
+	Rows      [][]Cell
+
+	// the sheet column after the last column is empty in the rows of the table, so a new column can be written there
+	NextColumnFree bool
+
+	// captured details of the cells, nil when nothing is captured
+	CellMeta map[CellRef]*CellMeta
+}
+
+func (t *Table) RowNumber(rowIndex int) int {
+	return t.HeaderRow + 1 + rowIndex
+}
+
+func (t *Table) ColumnIndex(name string) int {
+	for idx, col := range t.Columns {
+		if col.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (t *Table) Headers() []string {
+	headers := make([]string, len(t.Columns))
+	for idx, col := range t.Columns {
+		headers[idx] = col.Name
+	}
+	return headers
+}
+
+// SelectColumns keeps only the columns at the given indexes, in that order
+func (t *Table) SelectColumns(indexes []int) {
+	columns := make([]Column, len(indexes))
+	for i, idx := range indexes {
+		columns[i] = t.Columns[idx]
+	}
+	for r, row := range t.Rows {
+		newRow := make([]Cell, len(indexes))
+		for i, idx := range indexes {
+			newRow[i] = row[idx]
+		}
+		t.Rows[r] = newRow
+	}
+	t.Columns = columns
+}
+
+// AppendColumn adds a column filled with empty cells
+func (t *Table) AppendColumn(column Column) {
+	t.Columns = append(t.Columns, column)
+	for r := range t.Rows {
+		t.Rows[r] = append(t.Rows[r], Cell{Type: EmptyCell})
+	}
+}
@@ -0,0 +1,322 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+var (
+	numberRegex     = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+	errorValueRegex = regexp.MustCompile(`^(#NULL!|#DIV/0!|#VALUE!|#REF!|#NAME\?|#NUM!|#N/A|#ERROR!|#SPILL!|#CALC!)$`)
+	// date & time tokens of a number format, outside of quoted literals and colors
+	dateFormatTokenRegex  = regexp.MustCompile(`(?i)[dmyhs]`)
+	dateFormatIgnoreRegex = regexp.MustCompile(`"[^"]*"|\[[^\]]*\]|\\.`)
+)
+
+// built-in number formats of the spreadsheetml spec that render a date or a time
+var builtInDateNumFmts = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	27: true, 28: true, 29: true, 30: true, 31: true, 32: true, 33: true, 34: true, 35: true, 36: true,
+	45: true, 46: true, 47: true,
+	50: true, 51: true, 52: true, 53: true, 54: true, 55: true, 56: true, 57: true, 58: true,
+}
+
+func isDateFormatCode(code string) bool {
+	code = dateFormatIgnoreRegex.ReplaceAllString(code, "")
+	if strings.EqualFold(code, "general") {
+		return false
+	}
+	return dateFormatTokenRegex.MatchString(code)
+}
+
+type xlsxSheetReader struct {
+	file      *excelize.File
+	sheetName string
+	styles    map[int]cellStyle // style index -> style
+}
+
+type cellStyle struct {
+	isDate       bool
+	numberFormat NumberFormat
+}
+
+func (r *xlsxSheetReader) cellStyle(col, row int) (cellStyle, error) {
+	axis, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return cellStyle{}, err
+	}
+	styleIdx, err := r.file.GetCellStyle(r.sheetName, axis)
+	if err != nil {
+		return cellStyle{}, err
+	}
+	if style, ok := r.styles[styleIdx]; ok {
+		return style, nil
+	}
+	numFmtId, formatCode := r.numberFormat(styleIdx)
+	result := cellStyle{numberFormat: builtInNumberFormat(numFmtId)}
+	if formatCode != nil {
+		result.numberFormat = parseNumberFormatCode(*formatCode)
+	}
+	// durations are rendered with time tokens, they are numbers of days
+	if result.numberFormat.Kind == PlainNumber {
+		result.isDate = builtInDateNumFmts[numFmtId]
+		if formatCode != nil {
+			result.isDate = isDateFormatCode(*formatCode)
+		}
+	}
+	r.styles[styleIdx] = result
+	return result, nil
+}
+
+// numberFormat returns the number format id of the style & its code when it is a custom format.
+// It is read from the style sheet, excelize GetStyle returns the last custom format of the workbook for every style.
+func (r *xlsxSheetReader) numberFormat(styleIdx int) (int, *string) {
+	styles := r.file.Styles
+	if styles == nil || styles.CellXfs == nil || styleIdx < 0 || styleIdx >= len(styles.CellXfs.Xf) {
+		return 0, nil
+	}
+	numFmtIdPtr := styles.CellXfs.Xf[styleIdx].NumFmtID
+	if numFmtIdPtr == nil {
+		return 0, nil
+	}
+	numFmtId := *numFmtIdPtr
+	if styles.NumFmts != nil {
+		for _, numFmt := range styles.NumFmts.NumFmt {
+			if numFmt.NumFmtID == numFmtId {
+				return numFmtId, &numFmt.FormatCode
+			}
+		}
+	}
+	return numFmtId, nil
+}
+
+func (r *xlsxSheetReader) cellType(col, row int) (excelize.CellType, error) {
+	axis, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return excelize.CellTypeUnset, err
+	}
+	return r.file.GetCellType(r.sheetName, axis)
+}
+
+func (r *xlsxSheetReader) readCell(formatted, raw string, col, row int) (Cell, error) {
+	if raw == "" && formatted == "" {
+		return Cell{Type: EmptyCell}, nil
+	}
+	// texts looking like numbers or errors, e.g. zip codes & ids with leading zeros, are kept as written
+	if numberRegex.MatchString(raw) || errorValueRegex.MatchString(raw) {
+		cellType, err := r.cellType(col, row)
+		if err != nil {
+			return Cell{}, err
+		}
+		switch cellType {
+		case excelize.CellTypeSharedString, excelize.CellTypeInlineString:
+			return Cell{Type: StringCell, Value: formatted, Raw: raw}, nil
+		case excelize.CellTypeFormula:
+			// the text result of a formula is formatted as a number by excelize, its raw value is the text
+			return Cell{Type: StringCell, Value: raw, Raw: raw}, nil
+		}
+	}
+	switch {
+	case errorValueRegex.MatchString(raw):
+		return Cell{Type: ErrorCell, Value: raw, Raw: raw}, nil
+	case (raw == "1" || raw == "0") && (formatted == "TRUE" || formatted == "FALSE"):
+		return Cell{Type: BooleanCell, Value: strings.ToLower(formatted), Raw: raw}, nil
+	case numberRegex.MatchString(raw):
+		cell := Cell{Type: NumberCell, Value: normalizeNumber(raw), Raw: raw}
+		if formatted != raw {
+			style, err := r.cellStyle(col, row)
+			if err != nil {
+				return Cell{}, err
+			}
+			if style.isDate {
+				return Cell{Type: DateCell, Value: formatted, Raw: raw}, nil
+			}
+			cell.NumberFormat = style.numberFormat
+			if cell.NumberFormat.Kind == CurrencyNumber && cell.NumberFormat.Unit == "" {
+				// the currency of built-in formats depends on the locale, look for the symbol in the formatted value
+				cell.NumberFormat.Unit = currencyFromFormattedValue(formatted)
+			}
+		}
+		return cell, nil
+	default:
+		return Cell{Type: StringCell, Value: formatted, Raw: raw}, nil
+	}
+}
+
+func normalizeNumber(raw string) string {
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return raw
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// ReadXlsxSheet loads a sheet of a xlsx file, the header is expected in the first row.
+// Columns after the last header cell are ignored.
+func ReadXlsxSheet(filePath string, sheetName string) (*Table, error) {
+	return ReadXlsxSheetRegion(filePath, sheetName, Region{}, HeaderOptions{}, CaptureOptions{}, Limits{})
+}
+
+// ReadXlsxSheetRegion loads a region of a sheet, the header is located in the region by the header options.
+// Columns after the last header cell are ignored. When the region ends before an id column,
+// the id column is read too, as it is written next to the region. The details selected by capture are read into Table.CellMeta.
+// Sheets over the limits are rejected before their cells are read when possible.
+func ReadXlsxSheetRegion(filePath string, sheetName string, region Region, header HeaderOptions, capture CaptureOptions, limits Limits) (*Table, error) {
+	if err := header.Validate(); err != nil {
+		return nil, err
+	}
+	if err := limits.checkFileSize(filePath); err != nil {
+		return nil, err
+	}
+
+	file, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Error when opening xlsx file: %w", err)
+	}
+	defer file.Close()
+
+	if sheetName == "" {
+		sheetName = file.GetSheetName(0)
+	}
+	if idx, err := file.GetSheetIndex(sheetName); err != nil || idx == -1 {
+		return nil, ErrSheetNotFound
+	}
+	regionBounds, err := resolveRegion(file, sheetName, region)
+	if err != nil {
+		return nil, err
+	}
+	header = header.withDefaults()
+	if err := limits.checkDimension(file, sheetName, regionBounds, header.LastHeaderRow(regionBounds.firstRow)); err != nil {
+		return nil, err
+	}
+
+	formattedRows, err := file.GetRows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("Error when reading sheet %s: %w", sheetName, err)
+	}
+	rawRows, err := file.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("Error when reading raw values of sheet %s: %w", sheetName, err)
+	}
+
+	// 0-based indexes of the region in the rows
+	firstHeaderRow := header.FirstHeaderRow(regionBounds.firstRow) - 1
+	lastHeaderRow := header.LastHeaderRow(regionBounds.firstRow) - 1
+	firstCol := regionBounds.firstCol - 1
+	endRow := len(formattedRows)
+	if regionBounds.lastRow != 0 && regionBounds.lastRow < endRow {
+		endRow = regionBounds.lastRow
+	}
+	cellValue := func(rows [][]string, r, c int) string {
+		if r < len(rows) && c < len(rows[r]) {
+			return rows[r][c]
+		}
+		return ""
+	}
+
+	table := &Table{HeaderRow: lastHeaderRow + 1, NextColumnFree: true}
+	if firstHeaderRow >= endRow {
+		return table, nil
+	}
+
+	headerRows := make([][]string, 0, header.HeaderRowCount)
+	for r := firstHeaderRow; r <= lastHeaderRow && r < endRow; r++ {
+		headerRows = append(headerRows, append([]string{}, formattedRows[r]...))
+	}
+	if len(headerRows) > 1 {
+		// the group name of merged header cells applies to all of its columns
+		mergedCells, err := getMergedCells(file, sheetName)
+		if err != nil {
+			return nil, err
+		}
+		fillMergedCells(headerRows, mergedCells, firstHeaderRow)
+	}
+	for idx, row := range headerRows {
+		if firstCol < len(row) {
+			row = row[firstCol:]
+		} else {
+			row = nil
+		}
+		if regionBounds.lastCol != 0 && len(row) > regionBounds.lastCol-firstCol {
+			row = row[:regionBounds.lastCol-firstCol]
+		}
+		headerRows[idx] = row
+	}
+	names := MergeHeaderRows(headerRows, header.HeaderSeparator)
+	for len(names) > 0 && strings.TrimSpace(names[len(names)-1]) == "" {
+		names = names[:len(names)-1]
+	}
+	if regionBounds.lastCol != 0 && len(names) > 0 {
+		if len(names) == regionBounds.lastCol-firstCol && strings.TrimSpace(cellValue(formattedRows, lastHeaderRow, regionBounds.lastCol)) == IdColumnName {
+			names = append(names, IdColumnName)
+		}
+	}
+	if err := limits.checkColumns(len(names)); err != nil {
+		return nil, err
+	}
+	if err := limits.checkRows(endRow - lastHeaderRow - 1); err != nil {
+		return nil, err
+	}
+	table.Columns = make([]Column, len(names))
+	for idx, name := range names {
+		table.Columns[idx] = Column{Name: name, SourceIndex: firstCol + idx + 1}
+	}
+	if !region.IsWholeSheet() {
+		// the column after the header must be empty to write a new id column there
+		nextCol := firstCol + len(names)
+		for r := firstHeaderRow; r < endRow; r++ {
+			if strings.TrimSpace(cellValue(formattedRows, r, nextCol)) != "" {
+				table.NextColumnFree = false
+				break
+			}
+		}
+	}
+
+	reader := &xlsxSheetReader{
+		file:      file,
+		sheetName: sheetName,
+		styles:    make(map[int]cellStyle),
+	}
+	table.Rows = make([][]Cell, 0, endRow-lastHeaderRow)
+	for r := lastHeaderRow + 1; r < endRow; r++ {
+		row := make([]Cell, len(names))
+		for c := range names {
+			col := firstCol + c
+			if err := limits.checkCellLength(cellValue(formattedRows, r, col), col+1, r+1); err != nil {
+				return nil, err
+			}
+			row[c], err = reader.readCell(cellValue(formattedRows, r, col), cellValue(rawRows, r, col), col+1, r+1)
+			if err != nil {
+				return nil, fmt.Errorf("Error when reading cell at row %d, column %d: %w", r+1, col+1, err)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	if capture.Enabled() {
+		if err := captureCellMeta(file, sheetName, table, capture); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+func getMergedCells(file *excelize.File, sheetName string) ([]mergedCell, error) {
+	mergeCells, err := file.GetMergeCells(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("Error when reading merged cells of sheet %s: %w", sheetName, err)
+	}
+	result := make([]mergedCell, 0, len(mergeCells))
+	for _, mergeCell := range mergeCells {
+		merged, err := parseMergedCell(mergeCell.GetStartAxis(), mergeCell.GetEndAxis(), mergeCell.GetCellValue())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, merged)
+	}
+	return result, nil
+}
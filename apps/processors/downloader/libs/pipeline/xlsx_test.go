@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	excelize "github.com/xuri/excelize/v2"
+)
+
+func TestReadCell(t *testing.T) {
+	percentStyle := &excelize.Style{NumFmt: 10}
+	tests := []struct {
+		name    string
+		set     func(f *excelize.File)
+		raw     *rawCell
+		want    Cell
+		wantFmt NumberFormat
+	}{
+		{
+			name: "integer",
+			set:  func(f *excelize.File) { f.SetCellValue(fixtureSheet, "A2", 42) },
+			want: Cell{Type: NumberCell, Value: "42"},
+		},
+		{
+			name: "decimal",
+			set:  func(f *excelize.File) { f.SetCellValue(fixtureSheet, "A2", 1.5) },
+			want: Cell{Type: NumberCell, Value: "1.5"},
+		},
+		{
+			name: "text with leading zeros",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "00123") },
+			want: Cell{Type: StringCell, Value: "00123"},
+		},
+		{
+			name: "long text id",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "12345678901234567890") },
+			want: Cell{Type: StringCell, Value: "12345678901234567890"},
+		},
+		{
+			name: "text in scientific notation",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "1e5") },
+			want: Cell{Type: StringCell, Value: "1e5"},
+		},
+		{
+			name: "inline string",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "") },
+			raw:  &rawCell{Type: "inlineStr", Value: "007"},
+			want: Cell{Type: StringCell, Value: "007"},
+		},
+		{
+			name: "text result of formula",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "") },
+			raw:  &rawCell{Type: "str", Value: "0042"},
+			want: Cell{Type: StringCell, Value: "0042"},
+		},
+		{
+			name: "error",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "") },
+			raw:  &rawCell{Type: "e", Value: "#DIV/0!"},
+			want: Cell{Type: ErrorCell, Value: "#DIV/0!"},
+		},
+		{
+			name: "text looking like an error",
+			set:  func(f *excelize.File) { f.SetCellStr(fixtureSheet, "A2", "#N/A") },
+			want: Cell{Type: StringCell, Value: "#N/A"},
+		},
+		{
+			name: "boolean",
+			set:  func(f *excelize.File) { f.SetCellValue(fixtureSheet, "A2", true) },
+			want: Cell{Type: BooleanCell, Value: "true"},
+		},
+		{
+			name: "date",
+			set: func(f *excelize.File) {
+				f.SetCellValue(fixtureSheet, "A2", time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC))
+			},
+			want: Cell{Type: DateCell},
+		},
+		{
+			name: "percent",
+			set: func(f *excelize.File) {
+				style, _ := f.NewStyle(percentStyle)
+				f.SetCellValue(fixtureSheet, "A2", 0.25)
+				f.SetCellStyle(fixtureSheet, "A2", "A2", style)
+			},
+			want:    Cell{Type: NumberCell, Value: "0.25"},
+			wantFmt: NumberFormat{Kind: PercentNumber, Unit: PercentUnit},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeFixture(t, []string{"value"}, test.set)
+			if test.raw != nil {
+				setRawCells(t, filePath, map[string]rawCell{"A2": *test.raw})
+			}
+			table := readFixture(t, filePath)
+			if len(table.Rows) != 1 {
+				t.Fatalf("got %d rows, want 1", len(table.Rows))
+			}
+			got := table.Rows[0][0]
+			if got.Type != test.want.Type {
+				t.Fatalf("got type %d (%q), want %d", got.Type, got.Value, test.want.Type)
+			}
+			if test.want.Value != "" && got.Value != test.want.Value {
+				t.Errorf("got value %q, want %q", got.Value, test.want.Value)
+			}
+			if got.NumberFormat != test.wantFmt {
+				t.Errorf("got number format %+v, want %+v", got.NumberFormat, test.wantFmt)
+			}
+		})
+	}
+}
@@ -4,15 +4,31 @@ const HashedPrimaryField = "f_gfbbfabeggejigfgbfhdcdbecifcjhdd" // MD5 hash of O
 const OriginalPrimaryFieldName = "__StarionId"
 const PrimaryFieldName = "id"
 
+// captured hyperlinks, notes & formulas of the cells of a row, loaded to the row metadata
+const HashedCellMetaField = "f_effgaehfjdacgdcgffcbaffdgadfceeh" // MD5 hash of OriginalCellMetaFieldName
+const OriginalCellMetaFieldName = "__StarionCellMeta"
+
+// spreadsheet error kinds & cell references of the error values of a row, loaded to the row metadata
+const HashedCellErrorsField = "f_fajcadccdbedgfcccdaegbjcdbabaebd" // MD5 hash of OriginalCellErrorsFieldName
+const OriginalCellErrorsFieldName = "__StarionCellErrors"
+
 type DataType string
 
 const (
 	String  DataType = "String"
-	Number  DataType = "Number"
+	Number  DataType = "Number" // integer or decimal, schemas inferred before Integer & Decimal
 	Date    DataType = "Date"
 	Boolean DataType = "Boolean"
-	Array   DataType = "Array"
+	Array   DataType = "Array" // JSON array text in the snapshots, e.g. a multi-select column
 	Unknown DataType = "Unknown"
+
+	Integer  DataType = "Integer"
+	Decimal  DataType = "Decimal"
+	DateTime DataType = "DateTime"
+	Time     DataType = "Time"
+	Currency DataType = "Currency"
+	Percent  DataType = "Percent"
+	Duration DataType = "Duration"
 )
 
 type TableSchema map[string]FieldSchema
@@ -25,5 +41,11 @@ type FieldSchema struct {
 	Enum         []interface{} `json:"enum"`
 	Readonly     bool          `json:"readonly"`
 	Primary      bool          `json:"primary"`
-	// Format       string        `json:"format"`
+	Format       string        `json:"format,omitempty"`   // variant of the type, e.g. yes/no for a boolean
+	Unit         string        `json:"unit,omitempty"`     // currency code of a Currency, unit of a Percent or Duration
+	ItemType     DataType      `json:"itemType,omitempty"` // type of the items of an Array
+
+	// inference statistics, not part of the field definition
+	Confidence   float64 `json:"confidence,omitempty"`
+	SampledCount int     `json:"sampledCount,omitempty"`
 }
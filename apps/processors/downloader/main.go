@@ -2,8 +2,10 @@ package main
 
 import (
 	"downloader/pkg/config"
+	"downloader/pkg/job"
 	"downloader/pkg/logging"
 	"downloader/routers"
+	"downloader/service/retention"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,6 +17,8 @@ import (
 func init() {
 	config.Setup()
 	logging.Setup()
+	job.Setup()
+	retention.Setup()
 }
 
 func main() {
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"downloader/pkg/e"
+	"downloader/util/retry"
+	"shared/auth"
+	"shared/storage"
+
 	"github.com/caarlos0/env/v9"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
@@ -20,6 +25,36 @@ type IAppConfig struct {
 	S3AccessKey      string `env:"S3_ACCESS_KEY" envDefault:"admin"`
 	S3SecretKey      string `env:"S3_SECRET_KEY" envDefault:"abc123456"`
 	S3Ssl            bool   `env:"S3_SSL" envDefault:"false"`
+
+	// storage of the diff data bucket: s3, or local to keep the bucket in {STORAGE_LOCAL_DIR}/{S3_DIFF_DATA_BUCKET}
+	StorageBackend  string `env:"STORAGE_BACKEND" envDefault:"s3"`
+	StorageLocalDir string `env:"STORAGE_LOCAL_DIR" envDefault:"/var/lib/processors/storage"`
+
+	// sheets ingested at the same time by a batch ingest
+	IngestBatchConcurrency int `env:"INGEST_BATCH_CONCURRENCY" envDefault:"4"`
+
+	// size limits of an ingested sheet, 0 for no limit
+	MaxSheetRows      int   `env:"MAX_SHEET_ROWS" envDefault:"1000000"`
+	MaxSheetColumns   int   `env:"MAX_SHEET_COLUMNS" envDefault:"1000"`
+	MaxCellLength     int   `env:"MAX_CELL_LENGTH" envDefault:"50000"`
+	MaxSheetFileBytes int64 `env:"MAX_SHEET_FILE_BYTES" envDefault:"209715200"`
+
+	// sync versions kept in the diff data bucket: the last versions of each datasource & the versions of the last days
+	RetentionKeepVersions  int `env:"RETENTION_KEEP_VERSIONS" envDefault:"5"`
+	RetentionKeepDays      int `env:"RETENTION_KEEP_DAYS" envDefault:"7"`
+	RetentionSweepInterval int `env:"RETENTION_SWEEP_INTERVAL" envDefault:"0"` // seconds between sweeps, 0 to disable
+
+	// Jobs
+	JobStore string `env:"JOB_STORE" envDefault:"memory"`
+	JobTtl   int    `env:"JOB_TTL" envDefault:"86400"` // seconds to keep finished jobs
+
+	// OAuth clients used to refresh access tokens
+	GoogleClientId        string `env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret    string `env:"GOOGLE_CLIENT_SECRET"`
+	MicrosoftClientId     string `env:"MICROSOFT_CLIENT_ID"`
+	MicrosoftClientSecret string `env:"MICROSOFT_CLIENT_SECRET"`
+	MicrosoftTenant       string `env:"MICROSOFT_TENANT" envDefault:"common"`
+	TokenBrokerApiKey     string `env:"TOKEN_BROKER_API_KEY"`
 }
 
 var AppConfig = &IAppConfig{}
@@ -33,4 +68,41 @@ func Setup() {
 		log.Fatalf("Fail to parse config: %v", err)
 		panic(err)
 	}
+	// a batch ingest with no concurrency would wait forever
+	if AppConfig.IngestBatchConcurrency < 1 {
+		log.Fatalf("INGEST_BATCH_CONCURRENCY must be at least 1, got %d", AppConfig.IngestBatchConcurrency)
+	}
+}
+
+// StorageConfig is the storage of the diff data bucket
+func (c *IAppConfig) StorageConfig() storage.Config {
+	return storage.Config{
+		Backend:  c.StorageBackend,
+		Bucket:   c.S3DiffDataBucket,
+		LocalDir: c.StorageLocalDir,
+		S3: storage.S3Config{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			Ssl:       c.S3Ssl,
+		},
+	}
+}
+
+// AuthConfig is the default oauth clients of the providers, token requests are retried
+// & refresh failures are returned as external error with code e.TOKEN_REFRESH_FAILED
+func (c *IAppConfig) AuthConfig() auth.Config {
+	return auth.Config{
+		GoogleClientId:        c.GoogleClientId,
+		GoogleClientSecret:    c.GoogleClientSecret,
+		MicrosoftClientId:     c.MicrosoftClientId,
+		MicrosoftClientSecret: c.MicrosoftClientSecret,
+		MicrosoftTenant:       c.MicrosoftTenant,
+		TokenBrokerApiKey:     c.TokenBrokerApiKey,
+		HTTPClient:            retry.NewClient(),
+		RefreshError: func(err error) error {
+			return e.WrapExternalError(err, e.TOKEN_REFRESH_FAILED, "Cannot refresh access token")
+		},
+	}
 }
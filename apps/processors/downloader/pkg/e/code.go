@@ -23,6 +23,16 @@ const (
 	GOOGLE_DRIVE_FILE_UNAUTHORIZED = 1013
 	GOOGLE_DRIVE_FILE_FORBIDDEN    = 1014
 	SHEET_EMPTY                    = 1015
+	SHEET_NOT_FOUND                = 1016
+	INVALID_TIMEZONE               = 1017
+	REGION_INVALID                 = 1018
+	REGION_NOT_FOUND               = 1019
+	HEADER_OPTIONS_INVALID         = 1020
+	DATE_OPTIONS_INVALID           = 1021
+	LOCALE_INVALID                 = 1022
+	KEY_OPTIONS_INVALID            = 1023
+	QUALITY_RULES_INVALID          = 1024
+	RETENTION_INVALID              = 1025
 
 	WORKBOOK_UNAUTHORIZED = 1100
 	WORKBOOK_FORBIDDEN    = 1101
@@ -33,4 +43,23 @@ const (
 	WORKSHEET_UNKNOWN   = 1105
 
 	WORKSHEET_EMPTY = 1106
+
+	ID_COL_DUPLICATED = 1201
+	ID_COL_NO_SPACE   = 1202
+	ID_KEY_BLANK      = 1203
+	ID_KEY_DUPLICATED = 1204
+
+	REQUEST_THROTTLED    = 1301
+	TOKEN_REFRESH_FAILED = 1302
+
+	JOB_NOT_FOUND    = 1401
+	JOB_TYPE_INVALID = 1402
+	JOB_FINISHED     = 1403
+
+	QUALITY_RULE_FAILED = 1501
+
+	ROW_LIMIT_EXCEEDED         = 1601
+	COLUMN_LIMIT_EXCEEDED      = 1602
+	CELL_LENGTH_LIMIT_EXCEEDED = 1603
+	FILE_SIZE_LIMIT_EXCEEDED   = 1604
 )
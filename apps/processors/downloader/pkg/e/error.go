@@ -1,5 +1,7 @@
 package e
 
+import "errors"
+
 type ErrorType string
 
 const (
@@ -55,3 +57,13 @@ func WrapExternalError(err error, code int, msg string) *ExternalError {
 		Description: err.Error(),
 	}
 }
+
+// AsExternalError finds the external error in the chain of err,
+// the http client wraps the errors of its transport in *url.Error
+func AsExternalError(err error) (*ExternalError, bool) {
+	var externalErr *ExternalError
+	if errors.As(err, &externalErr) {
+		return externalErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,141 @@
+package job
+
+import (
+	"context"
+	"downloader/pkg/e"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForStatus polls the job until it has the status
+func waitForStatus(t *testing.T, manager *Manager, id string, status Status) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := manager.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		panics     bool
+		wantStatus Status
+		wantCode   int
+	}{
+		{name: "succeeded", wantStatus: StatusSucceeded},
+		{name: "failed", err: e.NewExternalErrorWithDescription(e.INVALID_PARAMS, "Invalid params", "failure"), wantStatus: StatusFailed, wantCode: e.INVALID_PARAMS},
+		{name: "failed with unknown error", err: errors.New("failure"), wantStatus: StatusFailed, wantCode: 500},
+		{name: "panicked", panics: true, wantStatus: StatusFailed, wantCode: 500},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := NewManager(NewMemoryStore(time.Hour))
+			release := make(chan struct{})
+			job, err := manager.Submit(context.Background(), "test", func(ctx context.Context) (interface{}, error) {
+				ReportStage(ctx, "work")
+				<-release
+				if test.panics {
+					panic("failure")
+				}
+				return "result", test.err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != StatusPending || job.Type != "test" {
+				t.Errorf("submitted job %+v, want a pending test job", job)
+			}
+
+			running := waitForStatus(t, manager, job.Id, StatusRunning)
+			if running.FinishedAt != nil {
+				t.Errorf("running job has finishedAt %v", running.FinishedAt)
+			}
+			close(release)
+
+			finished := waitForStatus(t, manager, job.Id, test.wantStatus)
+			if finished.FinishedAt == nil || finished.Stage != "work" {
+				t.Errorf("finished job %+v, want finishedAt & the reported stage", finished)
+			}
+			if test.wantStatus == StatusSucceeded {
+				if finished.Result != "result" || finished.Error != nil {
+					t.Errorf("got result %v & error %+v, want the result of the work", finished.Result, finished.Error)
+				}
+			} else if finished.Error == nil || finished.Error.Code != test.wantCode || finished.Result != nil {
+				t.Errorf("got result %v & error %+v, want error code %d", finished.Result, finished.Error, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	manager := NewManager(NewMemoryStore(time.Hour))
+	job, err := manager.Submit(context.Background(), "test", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, manager, job.Id, StatusRunning)
+
+	if _, err := manager.Cancel(context.Background(), job.Id); err != nil {
+		t.Fatal(err)
+	}
+	cancelled := waitForStatus(t, manager, job.Id, StatusCancelled)
+	if cancelled.Error != nil {
+		t.Errorf("cancelled job has error %+v", cancelled.Error)
+	}
+
+	_, err = manager.Cancel(context.Background(), job.Id)
+	var externalErr *e.ExternalError
+	if !errors.As(err, &externalErr) || externalErr.Code != e.JOB_FINISHED {
+		t.Errorf("cancel of a finished job: got error %v, want JOB_FINISHED", err)
+	}
+}
+
+func TestManagerUnknownJob(t *testing.T) {
+	manager := NewManager(NewMemoryStore(time.Hour))
+	for name, call := range map[string]func(ctx context.Context, id string) (*Job, error){"get": manager.Get, "cancel": manager.Cancel} {
+		_, err := call(context.Background(), "unknown")
+		var externalErr *e.ExternalError
+		if !errors.As(err, &externalErr) || externalErr.Code != e.JOB_NOT_FOUND {
+			t.Errorf("%s of an unknown job: got error %v, want JOB_NOT_FOUND", name, err)
+		}
+	}
+}
+
+func TestMemoryStoreRemovesExpiredJobs(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	finishedAt := time.Now().Add(-time.Hour)
+	for _, job := range []*Job{
+		{Id: "expired", Status: StatusSucceeded, FinishedAt: &finishedAt},
+		{Id: "running", Status: StatusRunning},
+	} {
+		if err := store.Create(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// expired jobs are removed on create
+	if err := store.Create(context.Background(), &Job{Id: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(context.Background(), "expired"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("got error %v for the expired job, want ErrJobNotFound", err)
+	}
+	if _, err := store.Get(context.Background(), "running"); err != nil {
+		t.Errorf("got error %v for the running job, want the job", err)
+	}
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("Job not found")
+
+// Store keeps the state of the jobs
+type Store interface {
+	Create(ctx context.Context, job *Job) error
+	// Get returns ErrJobNotFound when the job does not exist
+	Get(ctx context.Context, id string) (*Job, error)
+	Update(ctx context.Context, job *Job) error
+}
+
+// MemoryStore keeps the jobs in memory, finished jobs are removed after ttl
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:  ttl,
+		jobs: make(map[string]*Job),
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired()
+	jobCopy := *job
+	s.jobs[job.Id] = &jobCopy
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	jobCopy := *job
+	return &jobCopy, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.Id]; !ok {
+		return ErrJobNotFound
+	}
+	jobCopy := *job
+	s.jobs[job.Id] = &jobCopy
+	return nil
+}
+
+func (s *MemoryStore) removeExpired() {
+	if s.ttl <= 0 {
+		return
+	}
+	expiredBefore := time.Now().Add(-s.ttl)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(expiredBefore) {
+			delete(s.jobs, id)
+		}
+	}
+}
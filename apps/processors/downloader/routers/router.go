@@ -27,10 +27,21 @@ func InitRouter() *gin.Engine {
 
 	apiV1Excel := apiV1.Group("/excel")
 	apiV1Excel.POST("/download", v1.DownloadExcel)
+	apiV1Excel.POST("/preview", v1.PreviewExcel)
 
 	apiV1GoogleSheets := apiV1.Group("/google-sheets")
 	apiV1GoogleSheets.POST("/download", v1.DownloadGoogleSheets)
 	apiV1GoogleSheets.POST("/ingest", v1.IngestGoogleSheets)
+	apiV1GoogleSheets.POST("/ingest/batch", v1.BatchIngestGoogleSheets)
+	apiV1GoogleSheets.POST("/preview", v1.PreviewGoogleSheets)
+
+	apiV1Admin := apiV1.Group("/admin")
+	apiV1Admin.POST("/retention/sweep", v1.SweepRetention)
+	apiV1Admin.POST("/retention/purge", v1.PurgeRetention)
+
+	apiV1.POST("/jobs", v1.CreateJob)
+	apiV1.GET("/jobs/:id", v1.GetJob)
+	apiV1.DELETE("/jobs/:id", v1.CancelJob)
 
 	return r
 }
@@ -1,10 +1,13 @@
 package v1
 
 import (
+	"context"
+	"downloader/libs/pipeline"
 	"downloader/pkg/app"
 	"downloader/service/excel"
 	"fmt"
 	"net/http"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,14 +17,24 @@ type DownloadExcelRequest struct {
 	WorkbookId  string `form:"workbookId" valid:"Required"`
 	WorksheetId string `form:"worksheetId" valid:"Required"`
 	// WorksheetName string `form:"worksheetName" valid:"Required"`
-	AccessToken  string `form:"accessToken" valid:"Required"`
+	auth.Credentials
 	SessionId    string `form:"sessionId"`
 	DataSourceId string `form:"dataSourceId" valid:"Required"`
 	SyncVersion  *int   `form:"syncVersion" binding:"required,number"`
 	Timezone     string `form:"timezone" valid:"Required"`
+	// data region, an A1 range or an Excel table, the whole worksheet when omitted
+	Range     string `form:"range"`
+	TableName string `form:"tableName"`
+	pipeline.SheetOptions
+	// the cTag of the last download, the download is skipped when the content is unchanged
+	LastCTag string `form:"lastCTag"`
 }
 type DownloadExcelResponse struct {
-	Message string `json:"message"`
+	CTag        string `json:"cTag"`
+	ETag        string `json:"eTag"`
+	NotModified bool   `json:"notModified"`
+	// strftime format the dates written as text were read with, by column
+	DateFormats map[string]string `json:"dateFormats,omitempty"`
 }
 
 func DownloadExcel(c *gin.Context) {
@@ -36,27 +49,105 @@ func DownloadExcel(c *gin.Context) {
 		return
 	}
 
+	response, err := downloadExcel(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, response)
+}
+
+func downloadExcel(ctx context.Context, body DownloadExcelRequest) (*DownloadExcelResponse, error) {
 	excelService := excel.New(excel.MicrosoftExcelServiceInitParams{
 		DriveId:     body.DriveId,
 		WorkbookId:  body.WorkbookId,
 		WorksheetId: body.WorksheetId,
 		// WorksheetName: body.WorksheetName,
-		AccessToken:  body.AccessToken,
+		Credentials:  body.Credentials,
 		SessionId:    body.SessionId,
 		DataSourceId: body.DataSourceId,
 		SyncVersion:  *body.SyncVersion,
 		Timezone:     body.Timezone,
+		Range:        body.Range,
+		TableName:    body.TableName,
+		Options:      body.SheetOptions,
+		LastCTag:     body.LastCTag,
 	})
+	// the workbook session is closed whether the download succeeds or not
+	defer excelService.Close(ctx)
 
-	requestContext := c.Request.Context()
-	err = excelService.Download(requestContext)
+	result, err := excelService.Download(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running download excel for ds %s: %w", body.DataSourceId, err)
+	}
+
+	return &DownloadExcelResponse{
+		CTag:        result.CTag,
+		ETag:        result.ETag,
+		NotModified: result.NotModified,
+		DateFormats: result.DateFormats,
+	}, nil
+}
+
+// #########################################################################################################
+
+type PreviewExcelRequest struct {
+	DriveId     string `form:"driveId"`
+	WorkbookId  string `form:"workbookId" valid:"Required"`
+	WorksheetId string `form:"worksheetId" valid:"Required"`
+	auth.Credentials
+	Timezone string `form:"timezone" valid:"Required"`
+	// data region, an A1 range or an Excel table, the whole worksheet when omitted
+	Range     string `form:"range"`
+	TableName string `form:"tableName"`
+	pipeline.HeaderOptions
+	pipeline.ArrayOptions
+	pipeline.CaptureOptions
+	pipeline.DateOptions
+	pipeline.LocaleOptions
+	pipeline.KeyOptions
+	pipeline.QualityOptions
+	pipeline.PreviewOptions
+}
+
+// PreviewExcel reads a worksheet like a sync would, without writing to the workbook nor to the storage
+func PreviewExcel(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body PreviewExcelRequest
+	)
+
+	err := app.BindAndValid(c, &body)
 	if err != nil {
-		err = fmt.Errorf("Error running download excel for ds %s: %w", body.DataSourceId, err)
 		appG.Error(err)
 		return
 	}
 
-	excelService.Close(requestContext)
+	excelService := excel.New(excel.MicrosoftExcelServiceInitParams{
+		DriveId:     body.DriveId,
+		WorkbookId:  body.WorkbookId,
+		WorksheetId: body.WorksheetId,
+		Credentials: body.Credentials,
+		Timezone:    body.Timezone,
+		Range:       body.Range,
+		TableName:   body.TableName,
+		Options: pipeline.SheetOptions{
+			HeaderOptions:  body.HeaderOptions,
+			ArrayOptions:   body.ArrayOptions,
+			CaptureOptions: body.CaptureOptions,
+			DateOptions:    body.DateOptions,
+			LocaleOptions:  body.LocaleOptions,
+			KeyOptions:     body.KeyOptions,
+			QualityOptions: body.QualityOptions,
+		},
+	})
+	preview, err := excelService.Preview(c.Request.Context(), body.PreviewOptions)
+	excelService.Close(c.Request.Context())
+	if err != nil {
+		appG.Error(fmt.Errorf("Error running preview excel for workbook %s: %w", body.WorkbookId, err))
+		return
+	}
 
-	appG.Response(http.StatusOK, nil)
+	appG.Response(http.StatusOK, preview)
 }
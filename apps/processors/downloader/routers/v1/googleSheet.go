@@ -1,10 +1,13 @@
 package v1
 
 import (
+	"context"
+	"downloader/libs/pipeline"
 	"downloader/pkg/app"
 	google_sheets "downloader/service/google-sheets"
 	"fmt"
 	"net/http"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,14 +19,19 @@ type IngestGoogleSheetsRequest struct {
 	// SheetName     string `form:"sheetName" valid:"Required"`
 	// SheetIndex    *int64 `form:"sheetIndex" binding:"required,number"`
 	// TimeZone      string `form:"timeZone" valid:"Required"`
-	AccessToken  string `form:"accessToken" valid:"Required"`
+	auth.Credentials
 	DataSourceId string `form:"dataSourceId" valid:"Required"`
 	SyncVersion  *int   `form:"syncVersion" binding:"required,number"`
+	// data region, an A1 range or a named range, the whole sheet when omitted
+	Range      string `form:"range"`
+	NamedRange string `form:"namedRange"`
+	pipeline.SheetOptions
 }
 
-// type IngestGoogleSheetsResponse struct {
-// Message string `json:"message"`
-// }
+type IngestGoogleSheetsResponse struct {
+	// strftime format the dates written as text were read with, by column
+	DateFormats map[string]string `json:"dateFormats,omitempty"`
+}
 
 func IngestGoogleSheets(c *gin.Context) {
 	var (
@@ -37,6 +45,16 @@ func IngestGoogleSheets(c *gin.Context) {
 		return
 	}
 
+	response, err := ingestGoogleSheets(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, response)
+}
+
+func ingestGoogleSheets(ctx context.Context, body IngestGoogleSheetsRequest) (*IngestGoogleSheetsResponse, error) {
 	service := google_sheets.NewIngestService(google_sheets.GoogleSheetsIngestServiceInitParams{
 		DataProviderId: body.DataProviderId,
 		SpreadsheetId:  body.SpreadsheetId,
@@ -44,30 +62,104 @@ func IngestGoogleSheets(c *gin.Context) {
 		// SheetName:     body.SheetName,
 		// SheetIndex:    *body.SheetIndex,
 		// TimeZone:      body.TimeZone,
-		AccessToken:  body.AccessToken,
+		Credentials:  body.Credentials,
 		DataSourceId: body.DataSourceId,
 		SyncVersion:  *body.SyncVersion,
+		Range:        body.Range,
+		NamedRange:   body.NamedRange,
+		Options:      body.SheetOptions,
 	})
 
-	requestContext := c.Request.Context()
+	err := service.Setup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running setup google sheets ingest for ds %s: %w", body.DataSourceId, err)
+	}
+	defer service.Close(ctx)
+
+	result, err := service.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running ingest google sheets for ds %s: %w", body.DataSourceId, err)
+	}
+
+	return &IngestGoogleSheetsResponse{DateFormats: result.DateFormats}, nil
+}
+
+// #########################################################################################################
+
+type BatchIngestGoogleSheetsSheetRequest struct {
+	SheetId      string `form:"sheetId" binding:"required"`
+	DataSourceId string `form:"dataSourceId" binding:"required"`
+	SyncVersion  *int   `form:"syncVersion" binding:"required,number"`
+	Range        string `form:"range"`
+	NamedRange   string `form:"namedRange"`
+	pipeline.SheetOptions
+}
+type BatchIngestGoogleSheetsRequest struct {
+	DataProviderId string                                `form:"dataProviderId" valid:"Required"`
+	SpreadsheetId  string                                `form:"spreadsheetId" valid:"Required"`
+	Sheets         []BatchIngestGoogleSheetsSheetRequest `form:"sheets" binding:"required,min=1,dive"`
+	auth.Credentials
+}
+type BatchIngestGoogleSheetsResponse struct {
+	Results []google_sheets.BatchIngestSheetResult `json:"results"`
+}
+
+func BatchIngestGoogleSheets(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body BatchIngestGoogleSheetsRequest
+	)
 
-	err = service.Setup(requestContext)
+	err := app.BindAndValid(c, &body)
 	if err != nil {
-		err = fmt.Errorf("Error running setup google sheets ingest for ds %s: %w", body.DataSourceId, err)
 		appG.Error(err)
 		return
 	}
 
-	err = service.Run(requestContext)
+	response, err := batchIngestGoogleSheets(c.Request.Context(), body)
 	if err != nil {
-		err = fmt.Errorf("Error running ingest google sheets for ds %s: %w", body.DataSourceId, err)
 		appG.Error(err)
 		return
 	}
 
-	err = service.Close(requestContext)
+	appG.Response(http.StatusOK, response)
+}
 
-	appG.Response(http.StatusOK, nil)
+func batchIngestParams(body BatchIngestGoogleSheetsRequest) google_sheets.GoogleSheetsBatchIngestServiceInitParams {
+	sheets := make([]google_sheets.BatchIngestSheet, len(body.Sheets))
+	for i, sheet := range body.Sheets {
+		sheets[i] = google_sheets.BatchIngestSheet{
+			SheetId:      sheet.SheetId,
+			DataSourceId: sheet.DataSourceId,
+			SyncVersion:  *sheet.SyncVersion,
+			Range:        sheet.Range,
+			NamedRange:   sheet.NamedRange,
+			SheetOptions: sheet.SheetOptions,
+		}
+	}
+	return google_sheets.GoogleSheetsBatchIngestServiceInitParams{
+		DataProviderId: body.DataProviderId,
+		SpreadsheetId:  body.SpreadsheetId,
+		Sheets:         sheets,
+		Credentials:    body.Credentials,
+	}
+}
+
+func batchIngestGoogleSheets(ctx context.Context, body BatchIngestGoogleSheetsRequest) (*BatchIngestGoogleSheetsResponse, error) {
+	service := google_sheets.NewBatchIngestService(batchIngestParams(body))
+	defer service.Close(ctx)
+
+	err := service.Setup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running setup google sheets batch ingest for spreadsheet %s: %w", body.SpreadsheetId, err)
+	}
+
+	results, err := service.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running batch ingest google sheets for spreadsheet %s: %w", body.SpreadsheetId, err)
+	}
+
+	return &BatchIngestGoogleSheetsResponse{Results: results}, nil
 }
 
 // #########################################################################################################
@@ -75,10 +167,13 @@ func IngestGoogleSheets(c *gin.Context) {
 type DownloadGoogleSheetsRequest struct {
 	DataProviderId string `form:"dataProviderId" valid:"Required"`
 	SpreadsheetId  string `form:"spreadsheetId" valid:"Required"`
-	AccessToken    string `form:"accessToken" valid:"Required"`
+	auth.Credentials
+	// the spreadsheetVersion of the last download, the download is skipped when the spreadsheet is unchanged
+	LastSpreadsheetVersion string `form:"lastSpreadsheetVersion"`
 }
 type DownloadGoogleSheetsResponse struct {
 	SpreadsheetVersion string `json:"spreadsheetVersion"`
+	NotModified        bool   `json:"notModified"`
 }
 
 func DownloadGoogleSheets(c *gin.Context) {
@@ -93,36 +188,112 @@ func DownloadGoogleSheets(c *gin.Context) {
 		return
 	}
 
+	response, err := downloadGoogleSheets(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, response)
+}
+
+func downloadGoogleSheets(ctx context.Context, body DownloadGoogleSheetsRequest) (*DownloadGoogleSheetsResponse, error) {
 	service := google_sheets.NewDownloadService(google_sheets.GoogleSheetsDownloadServiceInitParams{
-		DataProviderId: body.DataProviderId,
-		SpreadsheetId:  body.SpreadsheetId,
-		AccessToken:    body.AccessToken,
+		DataProviderId:         body.DataProviderId,
+		SpreadsheetId:          body.SpreadsheetId,
+		LastSpreadsheetVersion: body.LastSpreadsheetVersion,
+		Credentials:            body.Credentials,
 	})
 
-	requestContext := c.Request.Context()
+	err := service.Setup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running setup google sheets download: %w", err)
+	}
 
-	err = service.Setup(requestContext)
+	result, err := service.Run(ctx)
 	if err != nil {
-		err = fmt.Errorf("Error running setup google sheets download: %w", err)
-		appG.Error(err)
-		return
+		return nil, fmt.Errorf("Error running download google sheets for data provider %s: %w", body.DataProviderId, err)
 	}
 
-	result, err := service.Run(requestContext)
+	err = service.Close(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running close google sheets download: %w", err)
+	}
+
+	return &DownloadGoogleSheetsResponse{
+		SpreadsheetVersion: result.SpreadsheetVersion,
+		NotModified:        result.NotModified,
+	}, nil
+}
+
+// #########################################################################################################
+
+type PreviewGoogleSheetsRequest struct {
+	SpreadsheetId string `form:"spreadsheetId" valid:"Required"`
+	SheetId       string `form:"sheetId" valid:"Required"`
+	auth.Credentials
+	// data region, an A1 range or a named range, the whole sheet when omitted
+	Range      string `form:"range"`
+	NamedRange string `form:"namedRange"`
+	pipeline.HeaderOptions
+	pipeline.ArrayOptions
+	pipeline.CaptureOptions
+	pipeline.DateOptions
+	pipeline.LocaleOptions
+	pipeline.KeyOptions
+	pipeline.QualityOptions
+	pipeline.PreviewOptions
+}
+
+// PreviewGoogleSheets reads a sheet like a sync would, without writing to the sheet nor to the storage
+func PreviewGoogleSheets(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body PreviewGoogleSheetsRequest
+	)
+
+	err := app.BindAndValid(c, &body)
 	if err != nil {
-		err = fmt.Errorf("Error running download google sheets for ds %s", err)
 		appG.Error(err)
 		return
 	}
 
-	err = service.Close(requestContext)
+	preview, err := previewGoogleSheets(c.Request.Context(), body)
 	if err != nil {
-		err = fmt.Errorf("Error running close google sheets download: %w", err)
 		appG.Error(err)
 		return
 	}
 
-	appG.Response(http.StatusOK, &DownloadGoogleSheetsResponse{
-		SpreadsheetVersion: result.SpreadsheetVersion,
+	appG.Response(http.StatusOK, preview)
+}
+
+func previewGoogleSheets(ctx context.Context, body PreviewGoogleSheetsRequest) (*pipeline.Preview, error) {
+	service := google_sheets.NewPreviewService(google_sheets.GoogleSheetsPreviewServiceInitParams{
+		SpreadsheetId: body.SpreadsheetId,
+		SheetId:       body.SheetId,
+		Credentials:   body.Credentials,
+		Range:         body.Range,
+		NamedRange:    body.NamedRange,
+		Header:        body.HeaderOptions,
+		Arrays:        body.ArrayOptions,
+		Capture:       body.CaptureOptions,
+		Dates:         body.DateOptions,
+		Locale:        body.LocaleOptions,
+		Keys:          body.KeyOptions,
+		Quality:       body.QualityOptions,
+		Preview:       body.PreviewOptions,
 	})
+
+	err := service.Setup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running setup google sheets preview: %w", err)
+	}
+	defer service.Close(ctx)
+
+	preview, err := service.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error running preview google sheets for spreadsheet %s: %w", body.SpreadsheetId, err)
+	}
+
+	return preview, nil
 }
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"downloader/libs/pipeline"
+	google_sheets "downloader/service/google-sheets"
+	"reflect"
+	"testing"
+)
+
+func TestBatchIngestParams(t *testing.T) {
+	min := 1.0
+	options := pipeline.SheetOptions{
+		HeaderOptions:  pipeline.HeaderOptions{SkipRows: 1, HeaderRow: 2, HeaderRowCount: 2, HeaderSeparator: " / "},
+		ArrayOptions:   pipeline.ArrayOptions{ArrayColumns: []string{"Tags"}, ArrayDelimiter: ";", DetectArrays: true},
+		CaptureOptions: pipeline.CaptureOptions{CaptureHyperlinks: true, CaptureNotes: true, CaptureFormulas: true},
+		DateOptions:    pipeline.DateOptions{DateOrder: "DMY", DateFormats: []string{"Due=%d/%m/%Y"}},
+		LocaleOptions:  pipeline.LocaleOptions{Locale: "de_DE"},
+		KeyOptions:     pipeline.KeyOptions{KeyColumns: []string{"Email"}},
+		IdOptions:      pipeline.IdOptions{HideIdColumn: true},
+		QualityOptions: pipeline.QualityOptions{QualityRules: []pipeline.QualityRule{{Column: "Age", Kind: "range", Min: &min, Blocking: true}}},
+	}
+	syncVersion := 4
+	params := batchIngestParams(BatchIngestGoogleSheetsRequest{
+		DataProviderId: "provider",
+		SpreadsheetId:  "spreadsheet",
+		Sheets: []BatchIngestGoogleSheetsSheetRequest{{
+			SheetId:      "1",
+			DataSourceId: "ds1",
+			SyncVersion:  &syncVersion,
+			Range:        "A1:C10",
+			SheetOptions: options,
+		}},
+	})
+
+	want := []google_sheets.BatchIngestSheet{{
+		SheetId:      "1",
+		DataSourceId: "ds1",
+		SyncVersion:  4,
+		Range:        "A1:C10",
+		SheetOptions: options,
+	}}
+	if params.DataProviderId != "provider" || params.SpreadsheetId != "spreadsheet" {
+		t.Errorf("batchIngestParams() = %+v, want the provider & spreadsheet of the request", params)
+	}
+	if !reflect.DeepEqual(params.Sheets, want) {
+		t.Errorf("batchIngestParams().Sheets = %+v, want %+v", params.Sheets, want)
+	}
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"downloader/pkg/app"
+	"downloader/pkg/e"
+	"downloader/pkg/job"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateJobRequest struct {
+	Type string `form:"type" valid:"Required"`
+	// the body of the matching synchronous endpoint
+	Params json.RawMessage `form:"params"`
+}
+
+// jobFactory validates the params of a job type & returns the work of the job
+type jobFactory func(params []byte) (job.RunFunc, error)
+
+var jobFactories = map[string]jobFactory{
+	"excel/download": func(params []byte) (job.RunFunc, error) {
+		var body DownloadExcelRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return downloadExcel(ctx, body)
+		}, nil
+	},
+	"google-sheets/download": func(params []byte) (job.RunFunc, error) {
+		var body DownloadGoogleSheetsRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return downloadGoogleSheets(ctx, body)
+		}, nil
+	},
+	"google-sheets/ingest": func(params []byte) (job.RunFunc, error) {
+		var body IngestGoogleSheetsRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return ingestGoogleSheets(ctx, body)
+		}, nil
+	},
+	"google-sheets/ingest-batch": func(params []byte) (job.RunFunc, error) {
+		var body BatchIngestGoogleSheetsRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return batchIngestGoogleSheets(ctx, body)
+		}, nil
+	},
+	"retention/sweep": func(params []byte) (job.RunFunc, error) {
+		var body SweepRetentionRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return sweepRetention(ctx, body)
+		}, nil
+	},
+	"retention/purge": func(params []byte) (job.RunFunc, error) {
+		var body PurgeRetentionRequest
+		if err := app.DecodeAndValid(params, &body); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return purgeRetention(ctx, body)
+		}, nil
+	},
+}
+
+func CreateJob(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body CreateJobRequest
+	)
+
+	err := app.BindAndValid(c, &body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	factory, ok := jobFactories[body.Type]
+	if !ok {
+		appG.Error(e.NewExternalErrorWithDescription(e.JOB_TYPE_INVALID, "Invalid job type", fmt.Sprintf("Job type %s is not supported", body.Type)))
+		return
+	}
+	run, err := factory(body.Params)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	createdJob, err := job.DefaultManager.Submit(c.Request.Context(), body.Type, run)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusAccepted, createdJob)
+}
+
+func GetJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	foundJob, err := job.DefaultManager.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, foundJob)
+}
+
+func CancelJob(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	cancelledJob, err := job.DefaultManager.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusAccepted, cancelledJob)
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"downloader/pkg/app"
+	"downloader/service/retention"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SweepRetentionRequest struct {
+	// sweep one datasource, all of them when omitted
+	DataSourceId string `form:"dataSourceId"`
+	// retention of the config when omitted
+	KeepVersions int  `form:"keepVersions"`
+	KeepDays     int  `form:"keepDays"`
+	DryRun       bool `form:"dryRun"`
+}
+
+type PurgeRetentionRequest struct {
+	DataSourceId string `form:"dataSourceId" valid:"Required"`
+	DryRun       bool   `form:"dryRun"`
+}
+
+func SweepRetention(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body SweepRetentionRequest
+	)
+
+	err := app.BindAndValid(c, &body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	response, err := sweepRetention(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, response)
+}
+
+func sweepRetention(ctx context.Context, body SweepRetentionRequest) (*retention.RetentionResult, error) {
+	return retention.New(retention.RetentionServiceInitParams{
+		DataSourceId: body.DataSourceId,
+		KeepVersions: body.KeepVersions,
+		KeepDays:     body.KeepDays,
+		DryRun:       body.DryRun,
+	}).Sweep(ctx)
+}
+
+func PurgeRetention(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		body PurgeRetentionRequest
+	)
+
+	err := app.BindAndValid(c, &body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	response, err := purgeRetention(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, response)
+}
+
+func purgeRetention(ctx context.Context, body PurgeRetentionRequest) (*retention.RetentionResult, error) {
+	return retention.New(retention.RetentionServiceInitParams{
+		DataSourceId: body.DataSourceId,
+		DryRun:       body.DryRun,
+	}).Purge(ctx)
+}
@@ -14,7 +14,7 @@ func WrapWorkbookApiError(code int, msg string) *e.ExternalError {
 	case 404:
 		return e.NewExternalErrorWithDescription(e.WORKBOOK_NOT_FOUND, "Workbook not found", msg)
 	default:
-		return e.NewExternalErrorWithDescription(e.WORKBOOK_UNKNOWN, fmt.Sprintf("Workbook unknow error (%s)", code), msg)
+		return e.NewExternalErrorWithDescription(e.WORKBOOK_UNKNOWN, fmt.Sprintf("Workbook unknow error (%d)", code), msg)
 	}
 }
 
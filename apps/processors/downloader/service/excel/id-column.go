@@ -0,0 +1,152 @@
+package excel
+
+import (
+	"bytes"
+	"context"
+	"downloader/libs/pipeline"
+	"fmt"
+	"io"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+	excelize "github.com/xuri/excelize/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+const maxUpdateIdBatchSize = 20000
+const maxConcurrentUpdateId = 4
+
+type UpdateRangeRequest struct {
+	Values [][]string `json:"values"`
+}
+
+func (s *MicrosoftExcelService) UpdateRangeValues(ctx context.Context, rangeAddress string, values [][]string) error {
+	s.logger.Debug("Updating range: ", rangeAddress)
+	url := fmt.Sprintf("%s/workbook/worksheets/%s/range(address='%s')?$select=address", s.getItemUrl(), s.worksheetId, rangeAddress)
+	bodyJSON, err := jsoniter.Marshal(UpdateRangeRequest{Values: values})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewReader(bodyJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("workbook-session-id", s.sessionId)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request to update range %s: %w", rangeAddress, err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response body from update range: %w", err)
+	}
+
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		var errRes ErrorResponse
+		err := jsoniter.Unmarshal(responseBody, &errRes)
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling: %w", err)
+		}
+		return WrapWorksheetApiError(resp.StatusCode, errRes.Error.Msg)
+	}
+	return nil
+}
+
+// WriteIds implements pipeline.IdWriter
+func (s *MicrosoftExcelService) WriteIds(ctx context.Context, request pipeline.IdWriteRequest) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrentUpdateId)
+	for firstRow, ids := range pipeline.GroupIdFixes(request.Fixes, maxUpdateIdBatchSize) {
+		firstCell, err := excelize.CoordinatesToCellName(request.Column, firstRow)
+		if err != nil {
+			return err
+		}
+		lastCell, err := excelize.CoordinatesToCellName(request.Column, firstRow+len(ids)-1)
+		if err != nil {
+			return err
+		}
+		values := make([][]string, len(ids))
+		for i, id := range ids {
+			values[i] = []string{id}
+		}
+		rangeAddress := firstCell + ":" + lastCell
+		group.Go(func() error {
+			return s.UpdateRangeValues(groupCtx, rangeAddress, values)
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	s.idsWritten = len(request.Fixes) > 0
+
+	if request.Hide {
+		return s.hideIdColumn(ctx, request.Column)
+	}
+	return nil
+}
+
+type RangeFormat struct {
+	ColumnHidden bool `json:"columnHidden"`
+}
+
+// hideIdColumn hides the id column when it is visible, the workbook is not changed once the column is hidden
+func (s *MicrosoftExcelService) hideIdColumn(ctx context.Context, column int) error {
+	columnName, err := excelize.ColumnNumberToName(column)
+	if err != nil {
+		return err
+	}
+	rangeAddress := columnName + ":" + columnName
+	responseBody, err := s.sendRangeFormatRequest(ctx, "GET", rangeAddress, nil)
+	if err != nil {
+		return err
+	}
+	var format RangeFormat
+	if err := jsoniter.Unmarshal(responseBody, &format); err != nil {
+		return fmt.Errorf("Error unmarshalling range format: %w", err)
+	}
+	if format.ColumnHidden {
+		return nil
+	}
+
+	s.logger.Info("Hiding id column ", columnName)
+	bodyJSON, err := jsoniter.Marshal(RangeFormat{ColumnHidden: true})
+	if err != nil {
+		return err
+	}
+	if _, err := s.sendRangeFormatRequest(ctx, "PATCH", rangeAddress, bodyJSON); err != nil {
+		return err
+	}
+	s.idsWritten = true
+	return nil
+}
+
+func (s *MicrosoftExcelService) sendRangeFormatRequest(ctx context.Context, method string, rangeAddress string, bodyJSON []byte) ([]byte, error) {
+	url := fmt.Sprintf("%s/workbook/worksheets/%s/range(address='%s')/format?$select=columnHidden", s.getItemUrl(), s.worksheetId, rangeAddress)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(bodyJSON))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("workbook-session-id", s.sessionId)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending request to format range %s: %w", rangeAddress, err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response body from format range: %w", err)
+	}
+
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		var errRes ErrorResponse
+		err := jsoniter.Unmarshal(responseBody, &errRes)
+		if err != nil {
+			return nil, fmt.Errorf("Error unmarshalling: %w", err)
+		}
+		return nil, WrapWorksheetApiError(resp.StatusCode, errRes.Error.Msg)
+	}
+	return responseBody, nil
+}
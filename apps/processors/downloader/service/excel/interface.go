@@ -25,3 +25,8 @@ type GetWorksheetInfoResponse struct {
 	Position   int    `json:"position"`
 	Visibility string `json:"visibility"`
 }
+
+type GetItemTagsResponse struct {
+	ETag string `json:"eTag"`
+	CTag string `json:"cTag"`
+}
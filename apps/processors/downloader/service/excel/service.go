@@ -3,19 +3,23 @@ package excel
 import (
 	"bytes"
 	"context"
+	"downloader/libs/pipeline"
 	"downloader/pkg/config"
 	"downloader/pkg/e"
+	"downloader/pkg/job"
 	"downloader/util"
+	"downloader/util/retry"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
-	"strconv"
+	"shared/auth"
+	"shared/storage"
 
 	jsoniter "github.com/json-iterator/go"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
 )
 
 type DownloadExternalError struct {
@@ -31,12 +35,22 @@ type MicrosoftExcelServiceInitParams struct {
 	WorksheetName string `json:"worksheetName"`
 
 	// auth
-	AccessToken string `json:"accessToken"`
-	SessionId   string `json:"sessionId"`
+	Credentials auth.Credentials `json:"credentials"`
+	SessionId   string           `json:"sessionId"`
 
 	DataSourceId string `json:"dataSourceId"`
 	SyncVersion  int    `json:"syncVersion"`
 	Timezone     string `json:"timezone"`
+
+	// data region of the worksheet, the whole worksheet when empty
+	Range     string `json:"range"`
+	TableName string `json:"tableName"`
+	// header, arrays, capture, dates, locale, keys, id & quality options of the worksheet,
+	// the locale is not read from the workbook as the Graph API does not expose its culture
+	Options pipeline.SheetOptions `json:"options"`
+
+	// cTag of the last download, the download is skipped while the content is unchanged
+	LastCTag string `json:"lastCTag"`
 }
 
 type MicrosoftExcelService struct {
@@ -48,7 +62,7 @@ type MicrosoftExcelService struct {
 	worksheetName string
 
 	// auth
-	accessToken string
+	tokenSource oauth2.TokenSource
 	sessionId   string
 
 	// data
@@ -56,10 +70,19 @@ type MicrosoftExcelService struct {
 	syncVersion  int
 	timezone     string
 
+	region  pipeline.Region
+	options pipeline.SheetOptions
+
 	driveInfo interface{}
 
+	// change detection
+	lastCTag   string
+	cTag       string
+	eTag       string
+	idsWritten bool
+
 	// client
-	httpClient http.Client
+	httpClient *http.Client
 
 	// loger
 	logger *log.Entry
@@ -77,25 +100,30 @@ func New(params MicrosoftExcelServiceInitParams) *MicrosoftExcelService {
 		"dataSourceId": params.DataSourceId,
 	})
 
-	// client
-	client := &http.Client{}
+	tokenSource := auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.MicrosoftProvider, params.Credentials)
 
 	return &MicrosoftExcelService{
 		driveId:       params.DriveId,
 		workbookId:    params.WorkbookId,
 		worksheetId:   params.WorksheetId,
 		worksheetName: params.WorksheetName,
-		accessToken:   params.AccessToken,
+		tokenSource:   tokenSource,
 		sessionId:     params.SessionId,
 		dataSourceId:  params.DataSourceId,
 		syncVersion:   params.SyncVersion,
 		timezone:      params.Timezone,
-		httpClient:    *client,
-		logger:        loggerEntry,
+		lastCTag:      params.LastCTag,
+		region: pipeline.Region{
+			Range: params.Range,
+			Table: params.TableName,
+		},
+		options:    params.Options,
+		httpClient: retry.NewOAuth2Client(tokenSource),
+		logger:     loggerEntry,
 	}
 }
 
-func (s *MicrosoftExcelService) CreateSessionId(persistChanges bool) error {
+func (s *MicrosoftExcelService) CreateSessionId(ctx context.Context, persistChanges bool) error {
 	s.logger.Debug("Creating session id")
 	var url string
 	if s.driveId == "" {
@@ -107,11 +135,10 @@ func (s *MicrosoftExcelService) CreateSessionId(persistChanges bool) error {
 		PersistChanges: persistChanges,
 	}
 	bodyJSON, err := jsoniter.Marshal(body)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(bodyJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(bodyJSON))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(bodyJSON)))
 	resp, err := s.httpClient.Do(req)
@@ -158,7 +185,6 @@ func (s *MicrosoftExcelService) CloseSession() error {
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("workbook-session-id", s.sessionId)
 	resp, err := s.httpClient.Do(req)
@@ -183,7 +209,7 @@ func (s *MicrosoftExcelService) CloseSession() error {
 	return nil
 }
 
-func (s *MicrosoftExcelService) GetWorksheetInfo() error {
+func (s *MicrosoftExcelService) GetWorksheetInfo(ctx context.Context) error {
 	s.logger.Debug("Getting worksheet info")
 	var url string
 	if s.driveId == "" {
@@ -191,15 +217,14 @@ func (s *MicrosoftExcelService) GetWorksheetInfo() error {
 	} else {
 		url = fmt.Sprintf("https://graph.microsoft.com/v1.0/drives/%s/items/%s/workbook/worksheets/%s", s.driveId, s.workbookId, s.worksheetId)
 	}
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
 	req.Header.Set("workbook-session-id", s.sessionId)
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return err
+		return fmt.Errorf("Error sending request to get worksheet info: %w", err)
 	}
 	defer resp.Body.Close()
 	responseBody, err := io.ReadAll(resp.Body)
@@ -231,80 +256,208 @@ func (source *MicrosoftExcelService) Setup(ctx context.Context) error {
 	return nil
 }
 
-func (source *MicrosoftExcelService) Download(ctx context.Context) error {
-	if err := source.CreateSessionId(true); err != nil {
-		source.logger.Error("Error creating session id", err)
-		return err
+func (s *MicrosoftExcelService) getItemUrl() string {
+	if s.driveId == "" {
+		return fmt.Sprintf("https://graph.microsoft.com/v1.0/me/drive/items/%s", s.workbookId)
 	}
-	if err := source.GetWorksheetInfo(); err != nil {
-		source.logger.Error("Error getting worksheet info", err)
+	return fmt.Sprintf("https://graph.microsoft.com/v1.0/drives/%s/items/%s", s.driveId, s.workbookId)
+}
+
+// GetItemTags gets the cTag (changed by content updates only) & the eTag of the workbook drive item
+func (s *MicrosoftExcelService) GetItemTags(ctx context.Context) error {
+	s.logger.Debug("Getting workbook tags")
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s?$select=cTag,eTag", s.getItemUrl()), nil)
+	if err != nil {
 		return err
 	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request to get workbook tags: %w", err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response body from get workbook tags: %w", err)
+	}
 
-	var debugParam string
-	if config.AppConfig.IsProduction {
-		debugParam = "off"
-	} else {
-		debugParam = "on"
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		var errRes ErrorResponse
+		if err := jsoniter.Unmarshal(responseBody, &errRes); err != nil {
+			return WrapWorkbookApiError(resp.StatusCode, resp.Status)
+		}
+		return WrapWorkbookApiError(resp.StatusCode, errRes.Error.Msg)
 	}
 
-	externalErrorFile, err := util.CreateTempFileWithContent("ext", "json", "{}")
+	var response GetItemTagsResponse
+	err = jsoniter.Unmarshal(responseBody, &response)
 	if err != nil {
-		return fmt.Errorf("Cannot generate temp file: %w", err)
-	}
-	defer util.DeleteFile(externalErrorFile)
-	s3Host, _ := util.ConvertS3URLToHost(config.AppConfig.S3Endpoint)
-
-	cmd := exec.CommandContext(
-		ctx,
-		"bash",
-		"./download-excel.sh",
-		"--externalErrorFile", externalErrorFile,
-		"--driveId", source.driveId,
-		"--workbookId", source.workbookId,
-		"--worksheetId", source.worksheetId,
-		"--worksheetName", source.worksheetName,
-		"--accessToken", source.accessToken,
-		"--sessionId", source.sessionId,
-		"--dataSourceId", source.dataSourceId,
-		"--syncVersion", fmt.Sprintf("%d", source.syncVersion),
-		"--timezone", source.timezone,
-		"--s3Endpoint", config.AppConfig.S3Endpoint,
-		"--s3Host", s3Host,
-		"--s3Region", config.AppConfig.S3Region,
-		"--s3Bucket", config.AppConfig.S3DiffDataBucket,
-		"--s3AccessKey", config.AppConfig.S3AccessKey,
-		"--s3SecretKey", config.AppConfig.S3SecretKey,
-		"--s3Ssl", strconv.FormatBool(config.AppConfig.S3Ssl),
-		"--debug", debugParam,
-	)
-
-	outputWriter := source.logger.WriterLevel(log.InfoLevel)
-	errorWriter := source.logger.WriterLevel(log.ErrorLevel)
-	defer outputWriter.Close()
-	defer errorWriter.Close()
-	cmd.Stdout = outputWriter
-	cmd.Stderr = errorWriter
-
-	if err := cmd.Run(); err != nil {
-		// check external error
-		log.Println("Check external error")
-		var externalError DownloadExternalError
-		marshalErr := util.MarshalJsonFile(externalErrorFile, &externalError)
-		if marshalErr != nil {
-			fmt.Errorf("Cannot read external error file: %w", err)
-		}
-		if externalError.Code != 0 {
-			return e.NewExternalErrorWithDescription(externalError.Code, externalError.Msg, "External error when running download script")
-		}
+		return err
+	}
+	s.logger.Debug("Workbook cTag: ", response.CTag)
+
+	s.cTag = response.CTag
+	s.eTag = response.ETag
+	return nil
+}
 
+func (s *MicrosoftExcelService) DownloadContent(ctx context.Context, filePath string) error {
+	s.logger.Debug("Downloading workbook content")
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/content", s.getItemUrl()), nil)
+	if err != nil {
 		return err
 	}
+	req.Header.Set("workbook-session-id", s.sessionId)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request to download workbook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		responseBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("Error reading response body from download workbook: %w", err)
+		}
+		var errRes ErrorResponse
+		if err := jsoniter.Unmarshal(responseBody, &errRes); err != nil {
+			return WrapWorkbookApiError(resp.StatusCode, resp.Status)
+		}
+		return WrapWorkbookApiError(resp.StatusCode, errRes.Error.Msg)
+	}
 
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Error creating workbook file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("Error writing workbook file: %w", err)
+	}
 	return nil
 }
 
+type DownloadResult struct {
+	CTag string
+	ETag string
+	// the content has not changed since the last download, nothing was ingested
+	NotModified bool
+	// strftime format the dates written as text were read with, by column
+	DateFormats map[string]string
+}
+
+func (source *MicrosoftExcelService) Download(ctx context.Context) (*DownloadResult, error) {
+	job.ReportStage(ctx, "download")
+	if err := source.GetItemTags(ctx); err != nil {
+		source.logger.Error("Error getting workbook tags", err)
+		return nil, err
+	}
+	if source.lastCTag != "" && source.lastCTag == source.cTag {
+		source.logger.Info("Workbook not modified since cTag ", source.cTag)
+		return &DownloadResult{
+			CTag:        source.cTag,
+			ETag:        source.eTag,
+			NotModified: true,
+		}, nil
+	}
+
+	// the ids hashed from key columns are not written back, the changes of the session are not persisted
+	if err := source.CreateSessionId(ctx, !source.options.KeyOptions.Enabled()); err != nil {
+		source.logger.Error("Error creating session id", err)
+		return nil, err
+	}
+	if err := source.GetWorksheetInfo(ctx); err != nil {
+		source.logger.Error("Error getting worksheet info", err)
+		return nil, err
+	}
+
+	workbookFile, err := util.GenerateTempFileName(source.workbookId, "xlsx", false)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot generate temp file: %w", err)
+	}
+	defer util.DeleteFile(workbookFile)
+	if err := source.DownloadContent(ctx, workbookFile); err != nil {
+		return nil, err
+	}
+
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	config := source.pipelineConfig()
+	config.IdWriter = source
+	config.Storage = storageHandler
+	ingestPipeline := pipeline.New(config)
+	result, err := ingestPipeline.Run(ctx, workbookFile, source.worksheetName)
+	if err != nil {
+		return nil, err
+	}
+	source.logger.Info("Ingested rows: ", result.RowCount)
+
+	// the written ids change the content, return the new tag so the next sync does not see its own change
+	if source.idsWritten {
+		if err := source.GetItemTags(ctx); err != nil {
+			source.logger.Error("Error getting workbook tags", err)
+			return nil, err
+		}
+	}
+
+	return &DownloadResult{
+		CTag:        source.cTag,
+		ETag:        source.eTag,
+		DateFormats: result.DateFormats,
+	}, nil
+}
+
+// pipelineConfig is the pipeline config of the worksheet, without id writer & storage
+func (source *MicrosoftExcelService) pipelineConfig() pipeline.Config {
+	return pipeline.Config{
+		DataSourceId:      source.dataSourceId,
+		SyncVersion:       source.syncVersion,
+		Timezone:          source.timezone,
+		Region:            source.region,
+		Options:           source.options,
+		SheetEmptyCode:    e.WORKSHEET_EMPTY,
+		SheetNotFoundCode: e.WORKSHEET_NOT_FOUND,
+		Logger:            source.logger,
+		Limits: pipeline.Limits{
+			MaxRows:       config.AppConfig.MaxSheetRows,
+			MaxColumns:    config.AppConfig.MaxSheetColumns,
+			MaxCellLength: config.AppConfig.MaxCellLength,
+			MaxFileBytes:  config.AppConfig.MaxSheetFileBytes,
+		},
+	}
+}
+
+// Preview reads the worksheet like Download, in a session without persisted changes,
+// nothing is written to the workbook nor uploaded
+func (source *MicrosoftExcelService) Preview(ctx context.Context, options pipeline.PreviewOptions) (*pipeline.Preview, error) {
+	if err := source.CreateSessionId(ctx, false); err != nil {
+		source.logger.Error("Error creating session id", err)
+		return nil, err
+	}
+	if err := source.GetWorksheetInfo(ctx); err != nil {
+		source.logger.Error("Error getting worksheet info", err)
+		return nil, err
+	}
+
+	workbookFile, err := util.GenerateTempFileName(source.workbookId, "xlsx", false)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot generate temp file: %w", err)
+	}
+	defer util.DeleteFile(workbookFile)
+	if err := source.DownloadContent(ctx, workbookFile); err != nil {
+		return nil, err
+	}
+
+	return pipeline.New(source.pipelineConfig()).Preview(ctx, workbookFile, source.worksheetName, options)
+}
+
 func (source *MicrosoftExcelService) Close(ctx context.Context) error {
+	// no session is created when the workbook is not modified
+	if source.sessionId == "" {
+		return nil
+	}
 	go func() {
 		if err := source.CloseSession(); err != nil {
 			source.logger.Warn("Error close session", err)
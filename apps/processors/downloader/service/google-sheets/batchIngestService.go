@@ -0,0 +1,169 @@
+package google_sheets
+
+import (
+	"context"
+	"downloader/libs/pipeline"
+	"downloader/pkg/config"
+	"downloader/pkg/e"
+	"downloader/pkg/job"
+	"downloader/util"
+	"fmt"
+	"os"
+	"shared/auth"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+type BatchIngestSheet struct {
+	SheetId      string `json:"sheetId"`
+	DataSourceId string `json:"dataSourceId"`
+	SyncVersion  int    `json:"syncVersion"`
+	Range        string `json:"range"`
+	NamedRange   string `json:"namedRange"`
+	pipeline.SheetOptions
+}
+
+type GoogleSheetsBatchIngestServiceInitParams struct {
+	// info
+	DataProviderId string             `json:"dataProviderId"`
+	SpreadsheetId  string             `json:"spreadsheetId"`
+	Sheets         []BatchIngestSheet `json:"sheets"`
+
+	// auth
+	Credentials auth.Credentials `json:"credentials"`
+}
+
+type BatchIngestSheetResult struct {
+	SheetId      string            `json:"sheetId"`
+	DataSourceId string            `json:"dataSourceId"`
+	SyncVersion  int               `json:"syncVersion"`
+	RowCount     int               `json:"rowCount"`
+	DateFormats  map[string]string `json:"dateFormats,omitempty"` // strftime format of the dates written as text, by column
+	Error        *e.ErrorResponse  `json:"error,omitempty"`
+}
+
+// GoogleSheetsBatchIngestService ingests many sheets of the saved spreadsheet from one local copy of the file
+type GoogleSheetsBatchIngestService struct {
+	// info
+	dataProviderId string
+	spreadsheetId  string
+	sheets         []BatchIngestSheet
+
+	// auth
+	tokenSource oauth2.TokenSource
+
+	// resource
+	spreadsheetFilePath string
+	spreadsheetMetadata *SpreadsheetMetadata
+
+	// loger
+	logger *log.Entry
+}
+
+func NewBatchIngestService(params GoogleSheetsBatchIngestServiceInitParams) *GoogleSheetsBatchIngestService {
+	// logger
+	logger := log.New()
+	logger.SetOutput(os.Stdout)
+	logger.SetFormatter(&log.JSONFormatter{})
+	logger.SetLevel(log.DebugLevel)
+	loggerEntry := logger.WithFields(log.Fields{
+		"spreadsheetId": params.SpreadsheetId,
+	})
+
+	return &GoogleSheetsBatchIngestService{
+		dataProviderId: params.DataProviderId,
+		spreadsheetId:  params.SpreadsheetId,
+		sheets:         params.Sheets,
+		tokenSource:    auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.GoogleProvider, params.Credentials),
+		logger:         loggerEntry,
+	}
+}
+
+func (s *GoogleSheetsBatchIngestService) Setup(ctx context.Context) error {
+	s.logger.Info("Setup google sheets batch ingest service")
+
+	filePath, spreadsheetMetadata, err := downloadSavedSpreadsheet(ctx, s.dataProviderId, s.spreadsheetId, s.logger)
+	if err != nil {
+		return err
+	}
+	s.spreadsheetFilePath = filePath
+	s.spreadsheetMetadata = spreadsheetMetadata
+	return nil
+}
+
+// Run ingests the sheets concurrently, a failed sheet does not stop the others
+func (s *GoogleSheetsBatchIngestService) Run(ctx context.Context) ([]BatchIngestSheetResult, error) {
+	results := make([]BatchIngestSheetResult, len(s.sheets))
+	var finished int32
+	// the stages of the sheets running together are not reported, only the count of ingested sheets
+	sheetCtx := job.WithoutStageReport(ctx)
+
+	group := errgroup.Group{}
+	group.SetLimit(config.AppConfig.IngestBatchConcurrency)
+	for i, sheet := range s.sheets {
+		i, sheet := i, sheet
+		group.Go(func() error {
+			results[i] = s.ingestSheet(sheetCtx, sheet)
+			job.ReportStage(ctx, fmt.Sprintf("ingested %d/%d sheets", atomic.AddInt32(&finished, 1), len(s.sheets)))
+			return nil
+		})
+	}
+	group.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// sheetParams are the ingest params of a sheet of the batch
+func (s *GoogleSheetsBatchIngestService) sheetParams(sheet BatchIngestSheet) GoogleSheetsIngestServiceInitParams {
+	return GoogleSheetsIngestServiceInitParams{
+		DataProviderId: s.dataProviderId,
+		SpreadsheetId:  s.spreadsheetId,
+		SheetId:        sheet.SheetId,
+		DataSourceId:   sheet.DataSourceId,
+		SyncVersion:    sheet.SyncVersion,
+		Range:          sheet.Range,
+		NamedRange:     sheet.NamedRange,
+		Options:        sheet.SheetOptions,
+	}
+}
+
+func (s *GoogleSheetsBatchIngestService) ingestSheet(ctx context.Context, sheet BatchIngestSheet) BatchIngestSheetResult {
+	result := BatchIngestSheetResult{
+		SheetId:      sheet.SheetId,
+		DataSourceId: sheet.DataSourceId,
+		SyncVersion:  sheet.SyncVersion,
+	}
+
+	service := newIngestService(s.sheetParams(sheet), s.tokenSource)
+	// the file is shared by the sheets, it is deleted on Close of the batch
+	service.spreadsheetFilePath = s.spreadsheetFilePath
+
+	err := service.setSheetInfo(s.spreadsheetMetadata)
+	if err == nil {
+		var ingestResult *pipeline.Result
+		ingestResult, err = service.ingest(ctx)
+		if err == nil {
+			result.RowCount = ingestResult.RowCount
+			result.DateFormats = ingestResult.DateFormats
+		}
+	}
+	if err != nil {
+		service.logger.Error("Error when ingesting sheet: ", err)
+		_, errResponse := e.NewErrorResponse(err)
+		result.Error = &errResponse
+	}
+	return result
+}
+
+func (s *GoogleSheetsBatchIngestService) Close(ctx context.Context) error {
+	if s.spreadsheetFilePath == "" {
+		return nil
+	}
+	return util.DeleteFile(s.spreadsheetFilePath)
+}
@@ -0,0 +1,75 @@
+package google_sheets
+
+import (
+	"downloader/libs/pipeline"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fillValue sets every field of a value to a non-zero value, so that a dropped option is noticed
+func fillValue(value reflect.Value, name string) {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			fillValue(value.Field(i), fmt.Sprintf("%s.%s", name, value.Type().Field(i).Name))
+		}
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), 1, 1)
+		fillValue(slice.Index(0), name)
+		value.Set(slice)
+	case reflect.Ptr:
+		value.Set(reflect.New(value.Type().Elem()))
+		fillValue(value.Elem(), name)
+	case reflect.String:
+		value.SetString(name)
+	case reflect.Int, reflect.Int64:
+		value.SetInt(int64(len(name)))
+	case reflect.Float64:
+		value.SetFloat(float64(len(name)))
+	case reflect.Bool:
+		value.SetBool(true)
+	default:
+		panic(fmt.Sprintf("fillValue: unsupported kind %s of %s", value.Kind(), name))
+	}
+}
+
+func TestBatchIngestSheetOptions(t *testing.T) {
+	var options pipeline.SheetOptions
+	fillValue(reflect.ValueOf(&options).Elem(), "options")
+
+	service := NewBatchIngestService(GoogleSheetsBatchIngestServiceInitParams{
+		DataProviderId: "provider",
+		SpreadsheetId:  "spreadsheet",
+		Sheets: []BatchIngestSheet{{
+			SheetId:      "1",
+			DataSourceId: "ds1",
+			SyncVersion:  3,
+			Range:        "A1:C10",
+			NamedRange:   "data",
+			SheetOptions: options,
+		}},
+	})
+	params := service.sheetParams(service.sheets[0])
+	want := GoogleSheetsIngestServiceInitParams{
+		DataProviderId: "provider",
+		SpreadsheetId:  "spreadsheet",
+		SheetId:        "1",
+		DataSourceId:   "ds1",
+		SyncVersion:    3,
+		Range:          "A1:C10",
+		NamedRange:     "data",
+		Options:        options,
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Fatalf("sheetParams() = %+v, want %+v", params, want)
+	}
+
+	config := newIngestService(params, service.tokenSource).pipelineConfig()
+	if !reflect.DeepEqual(config.Options, options) {
+		t.Errorf("pipelineConfig().Options = %+v, want %+v", config.Options, options)
+	}
+	if config.DataSourceId != "ds1" || config.SyncVersion != 3 || config.Region != (pipeline.Region{Range: "A1:C10", NamedRange: "data"}) {
+		t.Errorf("pipelineConfig() = %+v, want the data source, version & region of the sheet", config)
+	}
+}
@@ -21,6 +21,7 @@ func SerializeSpreadsheetFileMetadata(metadata SpreadsheetMetadata) (map[string]
 		"Spreadsheet_id":      &metadata.SpreadsheetId,
 		"Spreadsheet_version": &metadata.SpreadsheetVersion,
 		"Timezone":            &metadata.TimeZone,
+		"Locale":              &metadata.Locale,
 		"Sheets":              &sheetsMetadataBase64,
 	}
 	return result, nil
@@ -36,10 +37,16 @@ func DeserializeSpreadsheetFileMetadata(fileMetadata map[string]*string) (*Sprea
 	if err != nil {
 		return nil, err
 	}
+	// spreadsheets downloaded before the locale was saved have no locale
+	locale := ""
+	if fileMetadata["Locale"] != nil {
+		locale = *fileMetadata["Locale"]
+	}
 	return &SpreadsheetMetadata{
 		SpreadsheetId:      *fileMetadata["Spreadsheet_id"],
 		SpreadsheetVersion: *fileMetadata["Spreadsheet_version"],
 		TimeZone:           *fileMetadata["Timezone"],
+		Locale:             locale,
 		Sheets:             sheets,
 	}, nil
 }
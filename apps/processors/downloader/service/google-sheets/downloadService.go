@@ -4,20 +4,22 @@ import (
 	"context"
 	"downloader/pkg/config"
 	"downloader/pkg/e"
+	"downloader/pkg/job"
 	"downloader/util"
-	"downloader/util/s3"
+	"downloader/util/retry"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"shared/auth"
+	"shared/storage"
 	"strconv"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/drive/v3"
-	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 
@@ -26,12 +28,13 @@ import (
 
 type GoogleSheetsDownloadServiceInitParams struct {
 	// info
-	DataProviderId     string `json:"dataProviderId"`
-	SpreadsheetId      string `json:"spreadsheetId"`
-	SpreadsheetVersion int    `json:"syncVersion"`
+	DataProviderId string `json:"dataProviderId"`
+	SpreadsheetId  string `json:"spreadsheetId"`
+	// version of the last download, the spreadsheet is not exported again while it is unchanged
+	LastSpreadsheetVersion string `json:"lastSpreadsheetVersion"`
 
 	// auth
-	AccessToken string `json:"accessToken"`
+	Credentials auth.Credentials `json:"credentials"`
 }
 
 type GoogleSheetsDownloadService struct {
@@ -40,13 +43,16 @@ type GoogleSheetsDownloadService struct {
 	spreadsheetId  string
 
 	// auth
-	accessToken string
+	tokenSource oauth2.TokenSource
 
 	// data
-	spreadsheetVersion string
-	timeZone           string
+	lastSpreadsheetVersion string
+	spreadsheetVersion     string
+	timeZone               string
+	locale                 string
 
 	// service
+	httpClient   *http.Client
 	driveService *drive.Service
 	sheetService *sheets.Service
 
@@ -54,9 +60,9 @@ type GoogleSheetsDownloadService struct {
 	sheets      []*sheets.Sheet
 
 	// resource
-	downloadUrl     string
-	spreadsheetData *[]byte
-	metadata        map[string]*string
+	downloadUrl         string
+	spreadsheetFilePath string
+	metadata            map[string]*string
 
 	// loger
 	logger *log.Entry
@@ -64,6 +70,8 @@ type GoogleSheetsDownloadService struct {
 
 type DownloadResult struct {
 	SpreadsheetVersion string
+	// the spreadsheet has not changed since the last download, nothing was exported
+	NotModified bool
 }
 
 func NewDownloadService(params GoogleSheetsDownloadServiceInitParams) *GoogleSheetsDownloadService {
@@ -77,21 +85,18 @@ func NewDownloadService(params GoogleSheetsDownloadServiceInitParams) *GoogleShe
 	})
 
 	return &GoogleSheetsDownloadService{
-		dataProviderId: params.DataProviderId,
-		spreadsheetId:  params.SpreadsheetId,
-		accessToken:    params.AccessToken,
-		logger:         loggerEntry,
+		dataProviderId:         params.DataProviderId,
+		spreadsheetId:          params.SpreadsheetId,
+		lastSpreadsheetVersion: params.LastSpreadsheetVersion,
+		tokenSource:            auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.GoogleProvider, params.Credentials),
+		logger:                 loggerEntry,
 	}
 }
 
 func (s *GoogleSheetsDownloadService) Setup(ctx context.Context) error {
 	s.logger.Info("Setup google sheets service")
 
-	token := oauth2.Token{
-		AccessToken: s.accessToken,
-		// Expiry:      time.Now().Add(-30 * time.Minute),
-	}
-	tokenSource := oauth2.StaticTokenSource(&token)
+	s.httpClient = retry.NewOAuth2Client(s.tokenSource)
 
 	newCtx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -101,13 +106,13 @@ func (s *GoogleSheetsDownloadService) Setup(ctx context.Context) error {
 		// TODO: get spreadsheet file info
 		s.logger.Info("Get drive file info")
 		var err error
-		s.driveService, err = drive.NewService(newCtx, option.WithTokenSource(tokenSource))
+		s.driveService, err = drive.NewService(newCtx, option.WithHTTPClient(s.httpClient))
 		if err != nil {
 			return e.WrapInternalError(err, e.INIT_GOOGLE_DRIVE_SERVICE, "Init google drive service error")
 		}
-		driveFile, err := s.driveService.Files.Get(s.spreadsheetId).Fields("version", "exportLinks").Do()
+		driveFile, err := s.driveService.Files.Get(s.spreadsheetId).Fields("version", "exportLinks").Context(ctx).Do()
 		if err != nil {
-			return WrapGoogleDriveFileError(err.(*googleapi.Error))
+			return wrapGoogleDriveApiError(err)
 		}
 		link, err := url.Parse(driveFile.ExportLinks["application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"])
 		query := link.Query()
@@ -124,7 +129,7 @@ func (s *GoogleSheetsDownloadService) Setup(ctx context.Context) error {
 		// TODO: setup spreadsheet service
 		s.logger.Info("Get sheet info")
 		var err error
-		s.sheetService, err = sheets.NewService(newCtx, option.WithTokenSource(tokenSource))
+		s.sheetService, err = sheets.NewService(newCtx, option.WithHTTPClient(s.httpClient))
 		if err != nil {
 			return e.WrapInternalError(err, e.INIT_GOOGLE_SHEETS_SERVICE, "Init sheets service error")
 		}
@@ -142,43 +147,70 @@ func (s *GoogleSheetsDownloadService) Setup(ctx context.Context) error {
 
 func (s *GoogleSheetsDownloadService) Run(ctx context.Context) (*DownloadResult, error) {
 	s.logger.Info("Run download for spreadsheet ", s.spreadsheetId)
+	if s.lastSpreadsheetVersion != "" && s.lastSpreadsheetVersion == s.spreadsheetVersion {
+		s.logger.Info("Spreadsheet not modified since version ", s.spreadsheetVersion)
+		return &DownloadResult{
+			SpreadsheetVersion: s.spreadsheetVersion,
+			NotModified:        true,
+		}, nil
+	}
+	job.ReportStage(ctx, "download")
+	err := s.fetch(ctx)
+	if s.spreadsheetFilePath != "" {
+		defer util.DeleteFile(s.spreadsheetFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.ReportStage(ctx, "upload")
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: upload spreadsheet
+	spreadsheetMetadata, err := s.spreadsheetMetadata()
+	if err != nil {
+		return nil, err
+	}
+	spreadsheetFileMetadata, err := SerializeSpreadsheetFileMetadata(*spreadsheetMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("Error when serialize spreadsheet metadata: %w", err)
+	}
+	err = storage.UploadFile(ctx, storageHandler, GetSpreadSheetFileS3Key(s.dataProviderId), s.spreadsheetFilePath, spreadsheetFileMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("Error when upload spreadsheet: %w", err)
+	}
+
+	return &DownloadResult{
+		SpreadsheetVersion: s.spreadsheetVersion,
+	}, nil
+}
 
+// fetch exports the spreadsheet to a temp file & gets the spreadsheet & sheets info,
+// the caller deletes the file
+func (s *GoogleSheetsDownloadService) fetch(ctx context.Context) error {
 	group, _ := errgroup.WithContext(ctx)
 
 	group.Go(func() error {
 		// TODO: download spreadsheet
 		s.logger.Info("Downloading spreadsheet ", s.spreadsheetId)
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", s.downloadUrl, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", s.downloadUrl, nil)
 		if err != nil {
 			return err
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
-		req.Header.Set("Accept", "text/csv")
-
-		for {
-			resp, err := client.Do(req)
-			if err != nil {
-				return err
-			}
-
-			if resp.StatusCode == http.StatusOK {
-				fileBytes, err := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				if err != nil {
-					return err
-				}
-				s.spreadsheetData = &fileBytes
-				return nil
-			} else if resp.StatusCode == http.StatusTooManyRequests {
-				resp.Body.Close()
-				time.Sleep(5 * time.Second) // Wait for 5 seconds before retrying
-				s.logger.Info("Rate limit exceeded for downloading spreadsheet ", s.spreadsheetId)
-			} else {
-				resp.Body.Close()
-				return fmt.Errorf("Failed to download file: %s", resp.Status)
-			}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("Error when downloading spreadsheet: %w", err)
 		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Failed to download file: %s", resp.Status)
+		}
+
+		return s.saveSpreadsheetFile(resp.Body)
 	})
 
 	group.Go(func() error {
@@ -189,34 +221,25 @@ func (s *GoogleSheetsDownloadService) Run(ctx context.Context) (*DownloadResult,
 			"sheets.properties.index",
 			"sheets.properties.title",
 			"properties.timeZone",
-		).Do()
+			"properties.locale",
+		).Context(ctx).Do()
 		if err != nil {
-			return WrapSpreadSheetApiError(err.(*googleapi.Error))
+			return wrapGoogleApiError(err)
 		}
 		s.timeZone = spreadsheet.Properties.TimeZone
+		s.locale = spreadsheet.Properties.Locale
 		s.logger.Debug("Sheet timezone: ", s.timeZone)
+		s.logger.Debug("Sheet locale: ", s.locale)
 		s.sheets = spreadsheet.Sheets
 		return nil
 	})
 
-	err := group.Wait()
-	if err != nil {
-		return nil, err
-	}
-
-	handler, err := s3.NewHandlerWithConfig(&s3.S3HandlerConfig{
-		Endpoint:  config.AppConfig.S3Endpoint,
-		Region:    config.AppConfig.S3Region,
-		AccessKey: config.AppConfig.S3AccessKey,
-		SecretKey: config.AppConfig.S3SecretKey,
-		Bucket:    config.AppConfig.S3DiffDataBucket,
-	})
-	if err != nil {
-		return nil, err
-	}
+	return group.Wait()
+}
 
-	// TODO: upload spreadsheet
-	sheetNames := util.GetSheetNamesFromXlsxData(*s.spreadsheetData)
+// spreadsheetMetadata maps the sheets of the spreadsheet to the sheets of the fetched file
+func (s *GoogleSheetsDownloadService) spreadsheetMetadata() (*SpreadsheetMetadata, error) {
+	sheetNames, err := util.GetSheetNamesFromXlsxFile(s.spreadsheetFilePath)
 	sheetsMetadata := make(map[string]SheetsMetadata) // sheetId -> SheetMetadata
 	if err != nil {
 		return nil, err
@@ -224,30 +247,39 @@ func (s *GoogleSheetsDownloadService) Run(ctx context.Context) (*DownloadResult,
 	for _, sheet := range s.sheets {
 		sheetId := strconv.FormatInt(sheet.Properties.SheetId, 10)
 		sheetsMetadata[sheetId] = SheetsMetadata{
-			SheetId:    sheetId,
-			SheetIndex: sheet.Properties.Index,
-			SheetName:  sheet.Properties.Title,
-			XlsxSheetName:  sheetNames[sheet.Properties.Index],
+			SheetId:       sheetId,
+			SheetIndex:    sheet.Properties.Index,
+			SheetName:     sheet.Properties.Title,
+			XlsxSheetName: sheetNames[sheet.Properties.Index],
 		}
 	}
 	spreadsheetMetadata := &SpreadsheetMetadata{
 		SpreadsheetId:      s.spreadsheetId,
 		SpreadsheetVersion: s.spreadsheetVersion,
 		TimeZone:           s.timeZone,
+		Locale:             s.locale,
 		Sheets:             sheetsMetadata,
 	}
-	spreadsheetFileMetadata, err := SerializeSpreadsheetFileMetadata(*spreadsheetMetadata)
+	return spreadsheetMetadata, nil
+}
+
+// saveSpreadsheetFile streams the exported spreadsheet to a temp file
+func (s *GoogleSheetsDownloadService) saveSpreadsheetFile(body io.Reader) error {
+	filePath, err := util.GenerateTempFileName(s.dataProviderId, "xlsx", false)
 	if err != nil {
-		return nil, fmt.Errorf("Error when serialize spreadsheet metadata: %w", err)
+		return err
 	}
-	err = handler.UploadFileWithBytes(GetSpreadSheetFileS3Key(s.dataProviderId), *s.spreadsheetData, spreadsheetFileMetadata)
+	file, err := os.Create(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("Error when upload spreadsheet: %w", err)
+		return fmt.Errorf("Error when creating spreadsheet file: %w", err)
 	}
+	s.spreadsheetFilePath = filePath
+	defer file.Close()
 
-	return &DownloadResult{
-		SpreadsheetVersion: s.spreadsheetVersion,
-	}, nil
+	if _, err := io.Copy(file, body); err != nil {
+		return fmt.Errorf("Error when saving spreadsheet file: %w", err)
+	}
+	return nil
 }
 
 func (source *GoogleSheetsDownloadService) Close(ctx context.Context) error {
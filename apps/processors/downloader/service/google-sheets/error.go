@@ -16,7 +16,7 @@ func WrapSpreadSheetApiError(err *googleapi.Error) *e.ExternalError {
 	case 404:
 		return e.WrapExternalError(err.Unwrap(), e.SPREADSHEET_NOT_FOUND, "Spreadsheet not found")
 	default:
-		return e.WrapExternalError(err.Unwrap(), e.SPREADSHEET_UNKNOWN, fmt.Sprintf("Spreadsheet unknown error, status code = %s", err.Code))
+		return e.WrapExternalError(err.Unwrap(), e.SPREADSHEET_UNKNOWN, fmt.Sprintf("Spreadsheet unknown error, status code = %d", err.Code))
 	}
 }
 
@@ -29,6 +29,20 @@ func WrapGoogleDriveFileError(err *googleapi.Error) *e.ExternalError {
 	case 404:
 		return e.WrapExternalError(err.Unwrap(), e.GOOGLE_DRIVE_FILE_NOT_FOUND, "Google drive file not found")
 	default:
-		return e.WrapExternalError(err.Unwrap(), e.GOOGLE_DRIVE_FILE_UNKNOWN, fmt.Sprintf("Google drive file unknown error, status code = %s", err.Code))
+		return e.WrapExternalError(err.Unwrap(), e.GOOGLE_DRIVE_FILE_UNKNOWN, fmt.Sprintf("Google drive file unknown error, status code = %d", err.Code))
 	}
 }
+
+func wrapGoogleApiError(err error) error {
+	if googleapiErr, ok := err.(*googleapi.Error); ok {
+		return WrapSpreadSheetApiError(googleapiErr)
+	}
+	return err
+}
+
+func wrapGoogleDriveApiError(err error) error {
+	if googleapiErr, ok := err.(*googleapi.Error); ok {
+		return WrapGoogleDriveFileError(googleapiErr)
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package google_sheets
+
+import (
+	"context"
+	"downloader/libs/pipeline"
+	"downloader/util"
+	"downloader/util/retry"
+	"fmt"
+	"strconv"
+
+	excelize "github.com/xuri/excelize/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+const maxUpdateIdBatchSize = 40000
+
+// description of the protected range of the id column, it tells the range apart from the ranges of the users
+const idColumnProtectionDescription = "Starion id column, editing it breaks the sync of the rows"
+
+// WriteIds implements pipeline.IdWriter
+func (s *GoogleSheetsIngestService) WriteIds(ctx context.Context, request pipeline.IdWriteRequest) error {
+	sheetClient, err := sheets.NewService(ctx, option.WithHTTPClient(retry.NewOAuth2Client(s.tokenSource)))
+	if err != nil {
+		return fmt.Errorf("Error when creating client to update id col: %w", err)
+	}
+	sheetIdInt, err := strconv.ParseInt(s.sheetId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid sheet id %s: %w", s.sheetId, err)
+	}
+
+	if request.NewColumn {
+		res, err := sheetClient.Spreadsheets.Get(s.spreadsheetId).
+			Ranges(util.FormatSheetNameInRange(s.sheetName)).
+			IncludeGridData(false).
+			Fields(googleapi.Field("sheets.properties.gridProperties.columnCount")).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return wrapGoogleApiError(err)
+		}
+		columnCount := res.Sheets[0].Properties.GridProperties.ColumnCount
+		if columnCount < int64(request.Column) {
+			_, err = sheetClient.Spreadsheets.BatchUpdate(s.spreadsheetId, &sheets.BatchUpdateSpreadsheetRequest{
+				Requests: []*sheets.Request{
+					{
+						InsertDimension: &sheets.InsertDimensionRequest{
+							InheritFromBefore: true,
+							Range: &sheets.DimensionRange{
+								SheetId:    sheetIdInt,
+								Dimension:  "COLUMNS",
+								StartIndex: int64(request.Column - 1),
+								EndIndex:   int64(request.Column),
+							},
+						},
+					},
+				},
+			}).Context(ctx).Do()
+			if err != nil {
+				return wrapGoogleApiError(err)
+			}
+		}
+	}
+
+	if len(request.Fixes) > 0 {
+		if err := s.writeIdFixes(ctx, sheetClient, request); err != nil {
+			return err
+		}
+	}
+	if request.Hide {
+		return s.hideIdColumn(ctx, sheetClient, sheetIdInt, request.Column)
+	}
+	return nil
+}
+
+func (s *GoogleSheetsIngestService) writeIdFixes(ctx context.Context, sheetClient *sheets.Service, request pipeline.IdWriteRequest) error {
+	batches := pipeline.GroupIdFixes(request.Fixes, maxUpdateIdBatchSize)
+	updateData := make([]*sheets.ValueRange, 0, len(batches))
+	for firstRow, ids := range batches {
+		values := make([][]interface{}, len(ids))
+		for i, id := range ids {
+			values[i] = []interface{}{id}
+		}
+		updateData = append(updateData, &sheets.ValueRange{
+			Range:  fmt.Sprintf("%s!R%[2]dC%[3]d:R%[4]dC%[3]d", util.FormatSheetNameInRange(s.sheetName), firstRow, request.Column, firstRow+len(ids)-1),
+			Values: values,
+		})
+	}
+	_, err := sheetClient.Spreadsheets.Values.
+		BatchUpdate(s.spreadsheetId, &sheets.BatchUpdateValuesRequest{
+			ValueInputOption:        "RAW",
+			Data:                    updateData,
+			IncludeValuesInResponse: false,
+		}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return wrapGoogleApiError(err)
+	}
+	return nil
+}
+
+// hideIdColumn hides the id column & adds a warning-only protected range on it,
+// the hidden state & the protection are read first so that nothing is sent once they are set
+func (s *GoogleSheetsIngestService) hideIdColumn(ctx context.Context, sheetClient *sheets.Service, sheetId int64, column int) error {
+	columnName, err := excelize.ColumnNumberToName(column)
+	if err != nil {
+		return err
+	}
+	res, err := sheetClient.Spreadsheets.Get(s.spreadsheetId).
+		Ranges(fmt.Sprintf("%s!%[2]s1:%[2]s1", util.FormatSheetNameInRange(s.sheetName), columnName)).
+		Fields(googleapi.Field("sheets(protectedRanges(description,range),data(columnMetadata(hiddenByUser)))")).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return wrapGoogleApiError(err)
+	}
+
+	hidden, protected := false, false
+	if len(res.Sheets) > 0 {
+		sheet := res.Sheets[0]
+		if len(sheet.Data) > 0 && len(sheet.Data[0].ColumnMetadata) > 0 {
+			hidden = sheet.Data[0].ColumnMetadata[0].HiddenByUser
+		}
+		for _, protectedRange := range sheet.ProtectedRanges {
+			if protectedRange.Description == idColumnProtectionDescription && protectedRange.Range != nil && protectedRange.Range.StartColumnIndex == int64(column-1) {
+				protected = true
+			}
+		}
+	}
+
+	requests := make([]*sheets.Request, 0, 2)
+	if !hidden {
+		requests = append(requests, &sheets.Request{
+			UpdateDimensionProperties: &sheets.UpdateDimensionPropertiesRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:    sheetId,
+					Dimension:  "COLUMNS",
+					StartIndex: int64(column - 1),
+					EndIndex:   int64(column),
+				},
+				Properties: &sheets.DimensionProperties{HiddenByUser: true},
+				Fields:     "hiddenByUser",
+			},
+		})
+	}
+	if !protected {
+		requests = append(requests, &sheets.Request{
+			AddProtectedRange: &sheets.AddProtectedRangeRequest{
+				ProtectedRange: &sheets.ProtectedRange{
+					Range: &sheets.GridRange{
+						SheetId:          sheetId,
+						StartColumnIndex: int64(column - 1),
+						EndColumnIndex:   int64(column),
+					},
+					Description: idColumnProtectionDescription,
+					WarningOnly: true,
+				},
+			},
+		})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	s.logger.Info("Hiding & protecting id column ", columnName)
+	_, err = sheetClient.Spreadsheets.BatchUpdate(s.spreadsheetId, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Context(ctx).Do()
+	if err != nil {
+		return wrapGoogleApiError(err)
+	}
+	return nil
+}
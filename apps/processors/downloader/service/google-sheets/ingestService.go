@@ -2,15 +2,16 @@ package google_sheets
 
 import (
 	"context"
+	"downloader/libs/pipeline"
 	"downloader/pkg/config"
 	"downloader/pkg/e"
 	"downloader/util"
-	"downloader/util/s3"
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
+	"shared/auth"
+	"shared/storage"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/drive/v3"
 
@@ -37,10 +38,16 @@ type GoogleSheetsIngestServiceInitParams struct {
 	// SheetIndex    int64  `json:"sheetIndex"` // from 0
 
 	// auth
-	AccessToken string `json:"accessToken"`
+	Credentials auth.Credentials `json:"credentials"`
 
 	DataSourceId string `json:"dataSourceId"`
 	SyncVersion  int    `json:"syncVersion"`
+
+	// data region of the sheet, the whole sheet when empty
+	Range      string `json:"range"`
+	NamedRange string `json:"namedRange"`
+	// header, arrays, capture, dates, locale, keys, id & quality options of the sheet
+	Options pipeline.SheetOptions `json:"options"`
 }
 
 type GoogleSheetsIngestService struct {
@@ -51,9 +58,12 @@ type GoogleSheetsIngestService struct {
 	xlsxSheetName string
 	sheetIndex    int64
 	timeZone      string
+	locale        string
+	region        pipeline.Region
+	options       pipeline.SheetOptions
 
 	// auth
-	accessToken string
+	tokenSource oauth2.TokenSource
 
 	// data
 	dataProviderId string
@@ -68,6 +78,11 @@ type GoogleSheetsIngestService struct {
 }
 
 func NewIngestService(params GoogleSheetsIngestServiceInitParams) *GoogleSheetsIngestService {
+	return newIngestService(params, auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.GoogleProvider, params.Credentials))
+}
+
+// newIngestService creates the service with a token source shared by the sheets of a batch
+func newIngestService(params GoogleSheetsIngestServiceInitParams, tokenSource oauth2.TokenSource) *GoogleSheetsIngestService {
 	// logger
 	logger := log.New()
 	logger.SetOutput(os.Stdout)
@@ -88,139 +103,136 @@ func NewIngestService(params GoogleSheetsIngestServiceInitParams) *GoogleSheetsI
 		// sheetName:     params.SheetName,
 		// sheetIndex:    params.SheetIndex,
 		// timeZone:      params.TimeZone,
-		accessToken: params.AccessToken,
+		tokenSource: tokenSource,
 		syncVersion: params.SyncVersion,
-		logger:      loggerEntry,
+		region: pipeline.Region{
+			Range:      params.Range,
+			NamedRange: params.NamedRange,
+		},
+		options: params.Options,
+		logger:  loggerEntry,
 	}
 }
 
 func (s *GoogleSheetsIngestService) Setup(ctx context.Context) error {
 	s.logger.Info("Setup google sheets ingest service")
 
-	handler, err := s3.NewHandlerWithConfig(&s3.S3HandlerConfig{
-		Endpoint:  config.AppConfig.S3Endpoint,
-		Region:    config.AppConfig.S3Region,
-		AccessKey: config.AppConfig.S3AccessKey,
-		SecretKey: config.AppConfig.S3SecretKey,
-		Bucket:    config.AppConfig.S3DiffDataBucket,
-	})
+	filePath, spreadsheetMetadata, err := downloadSavedSpreadsheet(ctx, s.dataProviderId, s.spreadsheetId, s.logger)
 	if err != nil {
 		return err
 	}
+	s.spreadsheetFilePath = filePath
+
+	return s.setSheetInfo(spreadsheetMetadata)
+}
+
+// downloadSavedSpreadsheet downloads the spreadsheet stored by the download service to a temp file,
+// the caller deletes the file
+func downloadSavedSpreadsheet(ctx context.Context, dataProviderId string, spreadsheetId string, logger *log.Entry) (string, *SpreadsheetMetadata, error) {
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return "", nil, err
+	}
 	group, _ := errgroup.WithContext(ctx)
 
+	var filePath string
 	group.Go(func() error {
 		// TODO: download spreadsheet file
-		s.logger.Info("Downloading saved spreadsheet")
-		filePath, err := util.GenerateTempFileName(s.spreadsheetId, "xlsx", false)
-		if err != nil {
-			return err
-		}
-		err = handler.DownloadFile(GetSpreadSheetFileS3Key(s.dataProviderId), filePath)
+		logger.Info("Downloading saved spreadsheet")
+		tempFilePath, err := util.GenerateTempFileName(spreadsheetId, "xlsx", false)
 		if err != nil {
 			return err
 		}
-		s.spreadsheetFilePath = filePath
-		return nil
+		filePath = tempFilePath
+		return storage.DownloadFile(ctx, storageHandler, GetSpreadSheetFileS3Key(dataProviderId), filePath)
 	})
 
+	var spreadsheetMetadata *SpreadsheetMetadata
 	group.Go(func() error {
 		// TODO: get spreadsheet & sheets info
-		s.logger.Info("Get spreadsheet & sheets info")
-		fileMetadata, err := handler.GetObjectMetadata(GetSpreadSheetFileS3Key(s.dataProviderId))
+		logger.Info("Get spreadsheet & sheets info")
+		fileInfo, err := storageHandler.Stat(ctx, GetSpreadSheetFileS3Key(dataProviderId))
 		if err != nil {
 			return err
 		}
+		fileMetadata := fileInfo.Metadata
 		if len(fileMetadata) == 0 {
 			return fmt.Errorf("Spreadsheet file metadata not found")
 		}
-		spreadsheetMetadata, err := DeserializeSpreadsheetFileMetadata(fileMetadata)
-		if err != nil {
-			return err
-		}
-		
-		s.sheetName = spreadsheetMetadata.Sheets[s.sheetId].SheetName
-		s.xlsxSheetName = spreadsheetMetadata.Sheets[s.sheetId].XlsxSheetName
-		s.sheetIndex = spreadsheetMetadata.Sheets[s.sheetId].SheetIndex
-		s.timeZone = spreadsheetMetadata.TimeZone
-		s.logger.Debug("Sheet name: ", s.sheetName)
-		s.logger.Debug("Sheet index: ", s.sheetIndex)
-		s.logger.Debug("Time zone: ", s.timeZone)
-		return nil
+		spreadsheetMetadata, err = DeserializeSpreadsheetFileMetadata(fileMetadata)
+		return err
 	})
 
 	err = group.Wait()
 	if err != nil {
-		return err
+		if filePath != "" {
+			util.DeleteFile(filePath)
+		}
+		return "", nil, err
 	}
 
+	return filePath, spreadsheetMetadata, nil
+}
+
+func (s *GoogleSheetsIngestService) setSheetInfo(spreadsheetMetadata *SpreadsheetMetadata) error {
+	sheetMetadata, ok := spreadsheetMetadata.Sheets[s.sheetId]
+	if !ok {
+		return e.NewExternalErrorWithDescription(e.SHEET_NOT_FOUND, "Sheet not found", fmt.Sprintf("Sheet %s not found in spreadsheet", s.sheetId))
+	}
+	s.sheetName = sheetMetadata.SheetName
+	s.xlsxSheetName = sheetMetadata.XlsxSheetName
+	s.sheetIndex = sheetMetadata.SheetIndex
+	s.timeZone = spreadsheetMetadata.TimeZone
+	s.locale = spreadsheetMetadata.Locale
+	s.logger.Debug("Sheet name: ", s.sheetName)
+	s.logger.Debug("Sheet index: ", s.sheetIndex)
+	s.logger.Debug("Time zone: ", s.timeZone)
+	s.logger.Debug("Locale: ", s.locale)
 	return nil
 }
 
-func (s *GoogleSheetsIngestService) Run(ctx context.Context) error {
+func (s *GoogleSheetsIngestService) Run(ctx context.Context) (*pipeline.Result, error) {
+	return s.ingest(ctx)
+}
 
-	var debugParam string
-	if config.AppConfig.IsProduction {
-		debugParam = "off"
-	} else {
-		debugParam = "on"
+func (s *GoogleSheetsIngestService) ingest(ctx context.Context) (*pipeline.Result, error) {
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return nil, err
 	}
 
-	externalErrorFile, err := util.CreateTempFileWithContent("ext", "json", "{}")
+	config := s.pipelineConfig()
+	config.IdWriter = s
+	config.Storage = storageHandler
+	ingestPipeline := pipeline.New(config)
+	result, err := ingestPipeline.Run(ctx, s.spreadsheetFilePath, s.xlsxSheetName)
 	if err != nil {
-		return fmt.Errorf("Cannot generate temp file: %w", err)
+		return nil, err
 	}
-	s3Host, _ := util.ConvertS3URLToHost(config.AppConfig.S3Endpoint)
-	defer util.DeleteFile(externalErrorFile)
-
-	cmd := exec.CommandContext(
-		ctx,
-		"bash",
-		"./ingest-google-sheets.sh",
-		"--externalErrorFile", externalErrorFile,
-		"--spreadsheetId", s.spreadsheetId,
-		"--sheetId", s.sheetId,
-		"--sheetName", s.sheetName,
-		"--xlsxSheetName", s.xlsxSheetName,
-		"--sheetIndex", fmt.Sprintf("%d", s.sheetIndex+1),
-		"--spreadsheetFile", s.spreadsheetFilePath,
-		"--timezone", s.timeZone,
-		"--accessToken", s.accessToken,
-		"--dataSourceId", s.dataSourceId,
-		"--syncVersion", fmt.Sprintf("%d", s.syncVersion),
-		"--s3Endpoint", config.AppConfig.S3Endpoint,
-		"--s3Host", s3Host,
-		"--s3Region", config.AppConfig.S3Region,
-		"--s3Bucket", config.AppConfig.S3DiffDataBucket,
-		"--s3AccessKey", config.AppConfig.S3AccessKey,
-		"--s3SecretKey", config.AppConfig.S3SecretKey,
-		"--s3Ssl", strconv.FormatBool(config.AppConfig.S3Ssl),
-		"--debug", debugParam,
-	)
-
-	outputWriter := s.logger.WriterLevel(log.InfoLevel)
-	errorWriter := s.logger.WriterLevel(log.ErrorLevel)
-	defer outputWriter.Close()
-	defer errorWriter.Close()
-	cmd.Stdout = outputWriter
-	cmd.Stderr = errorWriter
-
-	if err := cmd.Run(); err != nil {
-		log.Debug("Error when running ingest script: ", err)
-		// check external error
-		var externalError DownloadExternalError
-		marshalErr := util.MarshalJsonFile(externalErrorFile, &externalError)
-		if marshalErr != nil {
-			return fmt.Errorf("Cannot read external error file: %w", err)
-		}
-		if externalError.Code != 0 {
-			return e.NewExternalErrorWithDescription(externalError.Code, externalError.Msg, "External error when running ingest script")
-		}
+	s.logger.Info("Ingested rows: ", result.RowCount)
 
-		return err
-	}
+	return result, nil
+}
 
-	return nil
+// pipelineConfig is the pipeline config of the sheet, without id writer & storage
+func (s *GoogleSheetsIngestService) pipelineConfig() pipeline.Config {
+	return pipeline.Config{
+		DataSourceId:      s.dataSourceId,
+		SyncVersion:       s.syncVersion,
+		Timezone:          s.timeZone,
+		Region:            s.region,
+		Options:           s.options,
+		SpreadsheetLocale: s.locale,
+		SheetEmptyCode:    e.SHEET_EMPTY,
+		SheetNotFoundCode: e.SHEET_NOT_FOUND,
+		Logger:            s.logger,
+		Limits: pipeline.Limits{
+			MaxRows:       config.AppConfig.MaxSheetRows,
+			MaxColumns:    config.AppConfig.MaxSheetColumns,
+			MaxCellLength: config.AppConfig.MaxCellLength,
+			MaxFileBytes:  config.AppConfig.MaxSheetFileBytes,
+		},
+	}
 }
 
 func (source *GoogleSheetsIngestService) Close(ctx context.Context) error {
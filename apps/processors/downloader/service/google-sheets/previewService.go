@@ -0,0 +1,94 @@
+package google_sheets
+
+import (
+	"context"
+	"downloader/libs/pipeline"
+	"downloader/util"
+	"shared/auth"
+)
+
+type GoogleSheetsPreviewServiceInitParams struct {
+	// info
+	SpreadsheetId string `json:"spreadsheetId"`
+	SheetId       string `json:"sheetId"`
+
+	// auth
+	Credentials auth.Credentials `json:"credentials"`
+
+	// data region of the sheet, the whole sheet when empty
+	Range      string `json:"range"`
+	NamedRange string `json:"namedRange"`
+	// same options as the ingest
+	Header  pipeline.HeaderOptions  `json:"header"`
+	Arrays  pipeline.ArrayOptions   `json:"arrays"`
+	Capture pipeline.CaptureOptions `json:"capture"`
+	Dates   pipeline.DateOptions    `json:"dates"`
+	Locale  pipeline.LocaleOptions  `json:"locale"`
+	Keys    pipeline.KeyOptions     `json:"keys"`
+	Quality pipeline.QualityOptions `json:"quality"`
+
+	Preview pipeline.PreviewOptions `json:"preview"`
+}
+
+// GoogleSheetsPreviewService reads a sheet like the download & the ingest would,
+// the exported spreadsheet is kept in a temp file, nothing is uploaded nor written to the sheet
+type GoogleSheetsPreviewService struct {
+	download *GoogleSheetsDownloadService
+	ingest   *GoogleSheetsIngestService
+	options  pipeline.PreviewOptions
+}
+
+func NewPreviewService(params GoogleSheetsPreviewServiceInitParams) *GoogleSheetsPreviewService {
+	download := NewDownloadService(GoogleSheetsDownloadServiceInitParams{
+		SpreadsheetId: params.SpreadsheetId,
+		Credentials:   params.Credentials,
+	})
+	ingest := newIngestService(GoogleSheetsIngestServiceInitParams{
+		SpreadsheetId: params.SpreadsheetId,
+		SheetId:       params.SheetId,
+		Range:         params.Range,
+		NamedRange:    params.NamedRange,
+		Options: pipeline.SheetOptions{
+			HeaderOptions:  params.Header,
+			ArrayOptions:   params.Arrays,
+			CaptureOptions: params.Capture,
+			DateOptions:    params.Dates,
+			LocaleOptions:  params.Locale,
+			KeyOptions:     params.Keys,
+			QualityOptions: params.Quality,
+		},
+	}, download.tokenSource)
+	return &GoogleSheetsPreviewService{
+		download: download,
+		ingest:   ingest,
+		options:  params.Preview,
+	}
+}
+
+func (s *GoogleSheetsPreviewService) Setup(ctx context.Context) error {
+	return s.download.Setup(ctx)
+}
+
+func (s *GoogleSheetsPreviewService) Run(ctx context.Context) (*pipeline.Preview, error) {
+	err := s.download.fetch(ctx)
+	if s.download.spreadsheetFilePath != "" {
+		defer util.DeleteFile(s.download.spreadsheetFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	spreadsheetMetadata, err := s.download.spreadsheetMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ingest.setSheetInfo(spreadsheetMetadata); err != nil {
+		return nil, err
+	}
+
+	s.ingest.spreadsheetFilePath = s.download.spreadsheetFilePath
+	return pipeline.New(s.ingest.pipelineConfig()).Preview(ctx, s.ingest.spreadsheetFilePath, s.ingest.xlsxSheetName, s.options)
+}
+
+func (s *GoogleSheetsPreviewService) Close(ctx context.Context) error {
+	return nil
+}
@@ -1,9 +1,9 @@
 package google_sheets
 
 type SheetsMetadata struct {
-	SheetId    string `json:"sheet_id"`
-	SheetIndex int64  `json:"sheet_index"`
-	SheetName  string `json:"sheet_name"`
+	SheetId       string `json:"sheet_id"`
+	SheetIndex    int64  `json:"sheet_index"`
+	SheetName     string `json:"sheet_name"`
 	XlsxSheetName string `json:"xlsx_sheet_name"`
 }
 
@@ -11,6 +11,7 @@ type SpreadsheetMetadata struct {
 	SpreadsheetId      string                    `json:"spreadsheet_id"`
 	SpreadsheetVersion string                    `json:"spreadsheet_version"`
 	TimeZone           string                    `json:"time_zone"`
+	Locale             string                    `json:"locale"`
 	Sheets             map[string]SheetsMetadata `json:"sheets"` // sheetId -> SheetMetadata
 }
 
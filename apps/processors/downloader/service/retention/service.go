@@ -0,0 +1,248 @@
+package retention
+
+import (
+	"context"
+	"downloader/pkg/config"
+	"downloader/pkg/e"
+	"fmt"
+	"os"
+	"regexp"
+	"shared/storage"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// prefixes of the objects written by sync version: data & schema of the downloader,
+// quality reports of the ingest & diff results of the comparer
+var versionedPrefixes = []string{"data/", "schema/", "quality/", "result/"}
+
+// {prefix}/{dataSourceId}-{version}[-{name}].{extension}, the spreadsheet files of data providers don't match
+var versionedKeyRegex = regexp.MustCompile(`^(data|schema|quality|result)/(.+)-(\d+)(?:-([A-Za-z]+))?\.(parquet|json)$`)
+
+type RetentionServiceInitParams struct {
+	// the datasource to sweep, all datasources when empty
+	DataSourceId string `json:"dataSourceId"`
+	// last versions kept by datasource, the config when 0
+	KeepVersions int `json:"keepVersions"`
+	// versions with an object newer than this are kept, the config when 0
+	KeepDays int `json:"keepDays"`
+	// list the objects to delete without deleting them
+	DryRun bool `json:"dryRun"`
+}
+
+type RetentionResult struct {
+	DryRun bool `json:"dryRun"`
+	// keys deleted, or to delete on dry run
+	Objects      []string `json:"objects"`
+	KeptCount    int      `json:"keptCount"`
+	DeletedCount int      `json:"deletedCount"`
+}
+
+type versionedObject struct {
+	storage.ObjectInfo
+	dataSourceId string
+	version      int
+	// the schema of the diff result, written by the comparer once the version is compared
+	resultSchema bool
+}
+
+// RetentionService deletes the objects of old sync versions from the diff data storage
+type RetentionService struct {
+	dataSourceId string
+	keepVersions int
+	keepWindow   time.Duration
+	dryRun       bool
+
+	logger *log.Entry
+}
+
+func New(params RetentionServiceInitParams) *RetentionService {
+	logger := log.New()
+	logger.SetOutput(os.Stdout)
+	logger.SetFormatter(&log.JSONFormatter{})
+	logger.SetLevel(log.DebugLevel)
+	loggerEntry := logger.WithFields(log.Fields{
+		"dataSourceId": params.DataSourceId,
+		"dryRun":       params.DryRun,
+	})
+
+	keepVersions := params.KeepVersions
+	if keepVersions == 0 {
+		keepVersions = config.AppConfig.RetentionKeepVersions
+	}
+	keepDays := params.KeepDays
+	if keepDays == 0 {
+		keepDays = config.AppConfig.RetentionKeepDays
+	}
+	return &RetentionService{
+		dataSourceId: params.DataSourceId,
+		keepVersions: keepVersions,
+		keepWindow:   time.Duration(keepDays) * 24 * time.Hour,
+		dryRun:       params.DryRun,
+		logger:       loggerEntry,
+	}
+}
+
+// Sweep keeps the last versions of each datasource & the versions in the time window, the other versions are deleted.
+// The last compared version, the one with a diff result schema, is always kept as the next sync is compared with it.
+func (s *RetentionService) Sweep(ctx context.Context) (*RetentionResult, error) {
+	if s.keepVersions < 1 {
+		return nil, e.NewExternalErrorWithDescription(e.RETENTION_INVALID, "Invalid retention", fmt.Sprintf("At least 1 version must be kept, got %d", s.keepVersions))
+	}
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return nil, err
+	}
+	return s.sweep(ctx, storageHandler, time.Now())
+}
+
+func (s *RetentionService) sweep(ctx context.Context, storageHandler storage.Storage, now time.Time) (*RetentionResult, error) {
+	objects, err := s.listObjects(ctx, storageHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	byDataSource := make(map[string][]versionedObject)
+	for _, object := range objects {
+		byDataSource[object.dataSourceId] = append(byDataSource[object.dataSourceId], object)
+	}
+	keptAfter := now.Add(-s.keepWindow)
+	deleting := make([]string, 0)
+	kept := 0
+	for _, dataSourceObjects := range byDataSource {
+		keptVersions := make(map[int]bool)
+		versions := make([]int, 0)
+		lastCompared := -1
+		for _, object := range dataSourceObjects {
+			if _, ok := keptVersions[object.version]; !ok {
+				versions = append(versions, object.version)
+				keptVersions[object.version] = false
+			}
+			if object.resultSchema && object.version > lastCompared {
+				lastCompared = object.version
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		for idx, version := range versions {
+			keptVersions[version] = idx < s.keepVersions || version == lastCompared
+		}
+		for _, object := range dataSourceObjects {
+			if object.LastModified.After(keptAfter) {
+				keptVersions[object.version] = true
+			}
+		}
+		for _, object := range dataSourceObjects {
+			if keptVersions[object.version] {
+				kept++
+			} else {
+				deleting = append(deleting, object.Key)
+			}
+		}
+	}
+	return s.delete(ctx, storageHandler, deleting, kept)
+}
+
+// Purge deletes all the versions of the datasource, used when the datasource is deleted
+func (s *RetentionService) Purge(ctx context.Context) (*RetentionResult, error) {
+	if s.dataSourceId == "" {
+		return nil, e.NewExternalErrorWithDescription(e.RETENTION_INVALID, "Invalid retention", "The datasource to purge is required")
+	}
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
+	if err != nil {
+		return nil, err
+	}
+	return s.purge(ctx, storageHandler)
+}
+
+func (s *RetentionService) purge(ctx context.Context, storageHandler storage.Storage) (*RetentionResult, error) {
+	objects, err := s.listObjects(ctx, storageHandler)
+	if err != nil {
+		return nil, err
+	}
+	deleting := make([]string, 0, len(objects))
+	for _, object := range objects {
+		deleting = append(deleting, object.Key)
+	}
+	return s.delete(ctx, storageHandler, deleting, 0)
+}
+
+// listObjects lists the versioned objects of the datasource, or of all datasources
+func (s *RetentionService) listObjects(ctx context.Context, storageHandler storage.Storage) ([]versionedObject, error) {
+	objects := make([]versionedObject, 0)
+	for _, prefix := range versionedPrefixes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		listPrefix := prefix
+		if s.dataSourceId != "" {
+			listPrefix += s.dataSourceId + "-"
+		}
+		infos, err := storageHandler.List(ctx, listPrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			match := versionedKeyRegex.FindStringSubmatch(info.Key)
+			// the prefix of a datasource also lists the datasources whose id starts with it
+			if match == nil || (s.dataSourceId != "" && match[2] != s.dataSourceId) {
+				continue
+			}
+			version, err := strconv.Atoi(match[3])
+			if err != nil {
+				continue
+			}
+			objects = append(objects, versionedObject{
+				ObjectInfo:   info,
+				dataSourceId: match[2],
+				version:      version,
+				resultSchema: match[1] == "result" && match[4] == "schema",
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (s *RetentionService) delete(ctx context.Context, storageHandler storage.Storage, keys []string, kept int) (*RetentionResult, error) {
+	sort.Strings(keys)
+	result := &RetentionResult{DryRun: s.dryRun, Objects: keys, KeptCount: kept, DeletedCount: len(keys)}
+	if s.dryRun || len(keys) == 0 {
+		s.logger.Info("Objects to delete: ", len(keys), ", kept: ", kept)
+		return result, nil
+	}
+
+	if err := storageHandler.Delete(ctx, keys); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Deleted objects: ", len(keys), ", kept: ", kept)
+	return result, nil
+}
+
+// #########################################################################################################
+
+// StartSweeper sweeps all datasources with the retention of the config every interval, until ctx is done
+func StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := New(RetentionServiceInitParams{}).Sweep(ctx); err != nil {
+					log.Error("Error when sweeping old versions: ", err)
+				}
+			}
+		}
+	}()
+}
+
+func Setup() {
+	if config.AppConfig.RetentionSweepInterval <= 0 {
+		return
+	}
+	StartSweeper(context.Background(), time.Duration(config.AppConfig.RetentionSweepInterval)*time.Second)
+}
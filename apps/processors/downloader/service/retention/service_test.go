@@ -0,0 +1,204 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"shared/storage"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryStorage lists & deletes objects of a map, the other methods are not used by the retention
+type memoryStorage struct {
+	storage.Storage
+	objects map[string]time.Time
+	deleted []string
+}
+
+func (st *memoryStorage) List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	infos := make([]storage.ObjectInfo, 0)
+	for key, lastModified := range st.objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, storage.ObjectInfo{Key: key, LastModified: lastModified})
+		}
+	}
+	return infos, nil
+}
+
+func (st *memoryStorage) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		delete(st.objects, key)
+	}
+	st.deleted = append(st.deleted, keys...)
+	return nil
+}
+
+var now = time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+// versionObjects are the objects of a downloaded version, with the diff result when compared
+func versionObjects(objects map[string]time.Time, dataSourceId string, version string, compared bool, lastModified time.Time) {
+	keys := []string{"data/%s-%s.parquet", "schema/%s-%s.json", "quality/%s-%s.json"}
+	if compared {
+		keys = append(keys, "result/%s-%s-schema.json", "result/%s-%s-addedRows.json")
+	}
+	for _, key := range keys {
+		objects[fmt.Sprintf(key, dataSourceId, version)] = lastModified
+	}
+}
+
+func TestSweep(t *testing.T) {
+	old := now.Add(-30 * 24 * time.Hour)
+	tests := []struct {
+		name        string
+		params      RetentionServiceInitParams
+		objects     func(objects map[string]time.Time)
+		wantDeleted []string
+	}{
+		{
+			name:   "keeps the last versions",
+			params: RetentionServiceInitParams{KeepVersions: 2, KeepDays: 1},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", false, old)
+				versionObjects(objects, "ds1", "2", false, old)
+				versionObjects(objects, "ds1", "3", true, old)
+			},
+			wantDeleted: []string{"data/ds1-1.parquet", "quality/ds1-1.json", "schema/ds1-1.json"},
+		},
+		{
+			name:   "keeps the last compared version",
+			params: RetentionServiceInitParams{KeepVersions: 1, KeepDays: 1},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", true, old)
+				versionObjects(objects, "ds1", "2", true, old)
+				versionObjects(objects, "ds1", "3", false, old)
+				versionObjects(objects, "ds1", "4", false, old)
+			},
+			wantDeleted: []string{
+				"data/ds1-1.parquet", "data/ds1-3.parquet", "quality/ds1-1.json", "quality/ds1-3.json",
+				"result/ds1-1-addedRows.json", "result/ds1-1-schema.json", "schema/ds1-1.json", "schema/ds1-3.json",
+			},
+		},
+		{
+			name:   "keeps the versions of the time window",
+			params: RetentionServiceInitParams{KeepVersions: 1, KeepDays: 7},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", false, old)
+				versionObjects(objects, "ds1", "2", false, now.Add(-2*24*time.Hour))
+				versionObjects(objects, "ds1", "3", true, now.Add(-time.Hour))
+			},
+			wantDeleted: []string{"data/ds1-1.parquet", "quality/ds1-1.json", "schema/ds1-1.json"},
+		},
+		{
+			name:   "keeps the versions by datasource",
+			params: RetentionServiceInitParams{KeepVersions: 1, KeepDays: 1},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", false, old)
+				versionObjects(objects, "ds1", "2", true, old)
+				versionObjects(objects, "ds10", "1", true, old)
+			},
+			wantDeleted: []string{"data/ds1-1.parquet", "quality/ds1-1.json", "schema/ds1-1.json"},
+		},
+		{
+			name:   "sweeps one datasource",
+			params: RetentionServiceInitParams{DataSourceId: "ds1", KeepVersions: 1, KeepDays: 1},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", false, old)
+				versionObjects(objects, "ds1", "2", true, old)
+				versionObjects(objects, "ds10", "1", false, old)
+				versionObjects(objects, "ds10", "2", true, old)
+			},
+			wantDeleted: []string{"data/ds1-1.parquet", "quality/ds1-1.json", "schema/ds1-1.json"},
+		},
+		{
+			name:   "ignores the spreadsheet files",
+			params: RetentionServiceInitParams{KeepVersions: 1, KeepDays: 1},
+			objects: func(objects map[string]time.Time) {
+				versionObjects(objects, "ds1", "1", true, old)
+				objects["spreadsheets/provider-1.xlsx"] = old
+				objects["data/ds1-latest.parquet"] = old
+			},
+			wantDeleted: []string{},
+		},
+	}
+	for _, test := range tests {
+		for _, dryRun := range []bool{false, true} {
+			name := test.name
+			if dryRun {
+				name += " on dry run"
+			}
+			t.Run(name, func(t *testing.T) {
+				store := &memoryStorage{objects: make(map[string]time.Time)}
+				test.objects(store.objects)
+				objectCount := len(store.objects)
+				params := test.params
+				params.DryRun = dryRun
+
+				result, err := New(params).sweep(context.Background(), store, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(result.Objects, test.wantDeleted) || result.DeletedCount != len(test.wantDeleted) {
+					t.Errorf("got objects %v, want %v", result.Objects, test.wantDeleted)
+				}
+				wantStored := objectCount - len(test.wantDeleted)
+				if dryRun {
+					wantStored = objectCount
+				}
+				if len(store.objects) != wantStored {
+					t.Errorf("got %d objects left, want %d", len(store.objects), wantStored)
+				}
+			})
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store := &memoryStorage{objects: make(map[string]time.Time)}
+	versionObjects(store.objects, "ds1", "1", true, now)
+	versionObjects(store.objects, "ds10", "1", true, now)
+	versionObjects(store.objects, "ds1-a", "1", true, now)
+
+	result, err := New(RetentionServiceInitParams{DataSourceId: "ds1"}).purge(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(store.deleted)
+	want := []string{"data/ds1-1.parquet", "quality/ds1-1.json", "result/ds1-1-addedRows.json", "result/ds1-1-schema.json", "schema/ds1-1.json"}
+	if !reflect.DeepEqual(store.deleted, want) || result.DeletedCount != len(want) {
+		t.Errorf("got deleted %v, want %v", store.deleted, want)
+	}
+}
+
+func TestVersionedKeyRegex(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string // prefix, datasource, version & name, nil when the key does not match
+	}{
+		{key: "data/ds1-3.parquet", want: []string{"data", "ds1", "3", ""}},
+		{key: "schema/ds1-3.json", want: []string{"schema", "ds1", "3", ""}},
+		{key: "quality/ds1-12.json", want: []string{"quality", "ds1", "12", ""}},
+		{key: "result/ds1-3-schema.json", want: []string{"result", "ds1", "3", "schema"}},
+		{key: "result/ds1-3-addedRows.json", want: []string{"result", "ds1", "3", "addedRows"}},
+		{key: "data/my-ds-10-3.parquet", want: []string{"data", "my-ds-10", "3", ""}},
+		{key: "data/ds1.parquet"},
+		{key: "data/ds1-3.csv"},
+		{key: "data/ds1-latest.parquet"},
+		{key: "spreadsheets/provider-3.json"},
+		{key: "backup/data/ds1-3.parquet"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			match := versionedKeyRegex.FindStringSubmatch(test.key)
+			var got []string
+			if match != nil {
+				got = match[1:5]
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
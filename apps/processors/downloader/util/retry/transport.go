@@ -0,0 +1,209 @@
+package retry
+
+import (
+	"downloader/pkg/e"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+type TransportConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// the longest Retry-After the transport waits for, it gives up when the server asks for more
+	MaxRetryAfter time.Duration
+
+	Base   http.RoundTripper
+	Logger *log.Entry
+}
+
+var DefaultTransportConfig = TransportConfig{
+	MaxAttempts:   6,
+	BaseDelay:     500 * time.Millisecond,
+	MaxDelay:      30 * time.Second,
+	MaxRetryAfter: 2 * time.Minute,
+}
+
+// Transport retries throttled (429, 503) & transient (500, 502, 504) responses with
+// jittered exponential backoff, honoring the Retry-After header.
+// Non idempotent requests (POST, PATCH) are only retried when throttled, as the server may have applied them on other failures.
+// When the requests are still throttled after the last attempt, it returns an external error with code e.REQUEST_THROTTLED
+type Transport struct {
+	config TransportConfig
+}
+
+func NewTransport(config TransportConfig) *Transport {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultTransportConfig.MaxAttempts
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = DefaultTransportConfig.BaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultTransportConfig.MaxDelay
+	}
+	if config.MaxRetryAfter <= 0 {
+		config.MaxRetryAfter = DefaultTransportConfig.MaxRetryAfter
+	}
+	if config.Base == nil {
+		config.Base = http.DefaultTransport
+	}
+	if config.Logger == nil {
+		config.Logger = log.NewEntry(log.StandardLogger())
+	}
+	return &Transport{config: config}
+}
+
+// NewClient returns a http client using the retrying transport with default config
+func NewClient() *http.Client {
+	return &http.Client{Transport: NewTransport(DefaultTransportConfig)}
+}
+
+// NewOAuth2Client returns a http client authorizing requests with the token source, on top of the retrying transport
+func NewOAuth2Client(tokenSource oauth2.TokenSource) *http.Client {
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: tokenSource,
+			Base:   NewTransport(DefaultTransportConfig),
+		},
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// the body can not be sent again without GetBody
+	canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("Error when rewinding request body: %w", err)
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.config.Base.RoundTrip(attemptReq)
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if !canRetry || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+		}
+		lastAttempt := attempt >= t.config.MaxAttempts || delay > t.config.MaxRetryAfter
+		if lastAttempt {
+			if resp != nil && isThrottled(resp.StatusCode) {
+				drainAndClose(resp)
+				return nil, e.NewExternalErrorWithDescription(
+					e.REQUEST_THROTTLED,
+					"Too many requests",
+					fmt.Sprintf("%s %s is still throttled (%d) after %d attempts", req.Method, req.URL.Redacted(), resp.StatusCode, attempt),
+				)
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			t.config.Logger.Warnf("Request %s %s failed with status %d, retrying in %s (attempt %d/%d)", req.Method, req.URL.Path, resp.StatusCode, delay, attempt, t.config.MaxAttempts)
+			drainAndClose(resp)
+		} else {
+			t.config.Logger.Warnf("Request %s %s failed: %v, retrying in %s (attempt %d/%d)", req.Method, req.URL.Path, err, delay, attempt, t.config.MaxAttempts)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the exponential delay of the attempt with equal jitter
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.config.MaxDelay
+	if attempt < 32 {
+		if exp := t.config.BaseDelay << uint(attempt-1); exp > 0 && exp < delay {
+			delay = exp
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if !isIdempotent(req.Method) {
+		// a throttled request was rejected before being applied
+		return err == nil && isThrottled(resp.StatusCode)
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isThrottled(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter parses the Retry-After header, in seconds or http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+func drainAndClose(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
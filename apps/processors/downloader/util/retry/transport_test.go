@@ -0,0 +1,224 @@
+package retry
+
+import (
+	"bytes"
+	"downloader/pkg/e"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// testServer answers the requests with the statuses in order, repeating the last one, & records the request bodies
+type testServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	headers  map[string]string
+	bodies   []string
+}
+
+func newTestServer(t *testing.T, headers map[string]string, statuses ...int) *testServer {
+	t.Helper()
+	server := &testServer{statuses: statuses, headers: headers}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		server.mutex.Lock()
+		attempt := len(server.bodies)
+		server.bodies = append(server.bodies, string(body))
+		server.mutex.Unlock()
+
+		status := server.statuses[len(server.statuses)-1]
+		if attempt < len(server.statuses) {
+			status = server.statuses[attempt]
+		}
+		for key, value := range server.headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *testServer) attempts() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.bodies)
+}
+
+func newTestClient(config TransportConfig) *http.Client {
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 2 * time.Millisecond
+	config.Logger = log.NewEntry(logger)
+	return &http.Client{Transport: NewTransport(config)}
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "get succeeded", method: http.MethodGet, statuses: []int{200}, wantStatus: 200, wantAttempts: 1},
+		{name: "get retried on 500", method: http.MethodGet, statuses: []int{500, 502, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "get retried on 429", method: http.MethodGet, statuses: []int{429, 200}, wantStatus: 200, wantAttempts: 2},
+		{name: "get not retried on 404", method: http.MethodGet, statuses: []int{404, 200}, wantStatus: 404, wantAttempts: 1},
+		{name: "post not retried on 500", method: http.MethodPost, statuses: []int{500, 200}, wantStatus: 500, wantAttempts: 1},
+		{name: "post not retried on 502", method: http.MethodPost, statuses: []int{502, 200}, wantStatus: 502, wantAttempts: 1},
+		{name: "post retried on 429", method: http.MethodPost, statuses: []int{429, 200}, wantStatus: 200, wantAttempts: 2},
+		{name: "post retried on 503", method: http.MethodPost, statuses: []int{503, 503, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "patch not retried on 500", method: http.MethodPatch, statuses: []int{500, 200}, wantStatus: 500, wantAttempts: 1},
+		{name: "get returns the last error after the attempts", method: http.MethodGet, statuses: []int{500}, wantStatus: 500, wantAttempts: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t, nil, test.statuses...)
+			client := newTestClient(TransportConfig{MaxAttempts: 3})
+
+			req, err := http.NewRequest(test.method, server.URL, strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if got := server.attempts(); got != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", got, test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestTransportAttemptCap(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			server := newTestServer(t, nil, http.StatusTooManyRequests)
+			client := newTestClient(TransportConfig{MaxAttempts: 4})
+
+			req, err := http.NewRequest(method, server.URL, strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.Do(req)
+			var externalErr *e.ExternalError
+			if !errors.As(err, &externalErr) || externalErr.Code != e.REQUEST_THROTTLED {
+				t.Errorf("got error %v, want REQUEST_THROTTLED", err)
+			}
+			if got := server.attempts(); got != 4 {
+				t.Errorf("got %d attempts, want 4", got)
+			}
+		})
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	t.Run("waits for the retry after", func(t *testing.T) {
+		server := newTestServer(t, map[string]string{"Retry-After": "1"}, http.StatusServiceUnavailable, http.StatusOK)
+		client := newTestClient(TransportConfig{MaxAttempts: 3})
+
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || server.attempts() != 2 {
+			t.Errorf("got status %d after %d attempts, want 200 after 2", resp.StatusCode, server.attempts())
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %s, want the 1s of Retry-After", elapsed)
+		}
+	})
+
+	t.Run("gives up when the retry after is too long", func(t *testing.T) {
+		server := newTestServer(t, map[string]string{"Retry-After": "120"}, http.StatusTooManyRequests, http.StatusOK)
+		client := newTestClient(TransportConfig{MaxAttempts: 3, MaxRetryAfter: time.Minute})
+
+		_, err := client.Get(server.URL)
+		var externalErr *e.ExternalError
+		if !errors.As(err, &externalErr) || externalErr.Code != e.REQUEST_THROTTLED {
+			t.Errorf("got error %v, want REQUEST_THROTTLED", err)
+		}
+		if got := server.attempts(); got != 1 {
+			t.Errorf("got %d attempts, want 1", got)
+		}
+	})
+}
+
+func TestTransportBodyReplay(t *testing.T) {
+	t.Run("replays the body", func(t *testing.T) {
+		server := newTestServer(t, nil, http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK)
+		client := newTestClient(TransportConfig{MaxAttempts: 3})
+
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := server.bodies; len(got) != 3 || got[0] != "payload" || got[1] != "payload" || got[2] != "payload" {
+			t.Errorf("got bodies %q, want the payload on each attempt", got)
+		}
+	})
+
+	t.Run("does not retry a body without GetBody", func(t *testing.T) {
+		server := newTestServer(t, nil, http.StatusServiceUnavailable, http.StatusOK)
+		client := newTestClient(TransportConfig{MaxAttempts: 3})
+
+		// a reader of unknown type has no GetBody
+		req, err := http.NewRequest(http.MethodPut, server.URL, io.MultiReader(bytes.NewReader([]byte("payload"))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || server.attempts() != 1 {
+			t.Errorf("got status %d after %d attempts, want 503 after 1", resp.StatusCode, server.attempts())
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{value: "", wantOk: false},
+		{value: "3", want: 3 * time.Second, wantOk: true},
+		{value: "-1", wantOk: false},
+		{value: "soon", wantOk: false},
+		{value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), want: 0, wantOk: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(test.value)
+			if got != test.want || ok != test.wantOk {
+				t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", test.value, got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, %v, want about 1h", future, got, ok)
+	}
+}
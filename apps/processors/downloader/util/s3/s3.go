@@ -13,11 +13,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	log "github.com/hiyali/logli"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 const (
-	S3_DEFAULT_ACL = "private"
+	S3_DEFAULT_ACL        = "private"
+	S3_UPLOAD_PART_SIZE   = 16 * 1024 * 1024
+	S3_UPLOAD_CONCURRENCY = 4
 )
 
 type S3HandlerConfig struct {
@@ -72,23 +74,42 @@ func NewHandlerWithConfig(config *S3HandlerConfig) (*S3Handler, error) {
 }
 
 func (h S3Handler) UploadFile(key string, filename string) error {
+	return h.UploadFileWithMetadata(key, filename, nil)
+}
+
+func (h S3Handler) UploadFileWithMetadata(key string, filename string, metadata map[string]*string) error {
 	file, err := os.Open(filename)
 	if err != nil {
-		log.FatalF("os.Open - filename: %s, err: %v", filename, err)
+		return fmt.Errorf("Error when opening file %s: %w", filename, err)
 	}
 	defer file.Close()
 
-	// buffer := []byte(body)
+	return h.UploadStream(key, file, metadata)
+}
 
-	_, err = s3.New(h.Session).PutObject(&s3.PutObjectInput{
+// UploadStream uploads the reader with multipart upload, only a few parts are buffered in memory at a time
+func (h S3Handler) UploadStream(key string, body io.Reader, metadata map[string]*string) error {
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(h.Bucket),
 		Key:    aws.String(key),
 		ACL:    aws.String(S3_DEFAULT_ACL),
-		Body:   file, // bytes.NewReader(buffer),
-		// ContentDisposition: aws.String("attachment"),
+		Body:   body,
+	}
+
+	if metadata != nil {
+		input.Metadata = metadata
+	}
+
+	uploader := s3manager.NewUploader(h.Session, func(u *s3manager.Uploader) {
+		u.PartSize = S3_UPLOAD_PART_SIZE
+		u.Concurrency = S3_UPLOAD_CONCURRENCY
 	})
+	_, err := uploader.Upload(input)
+	if err != nil {
+		return fmt.Errorf("Error when upload file to s3: %w", err)
+	}
 
-	return err
+	return nil
 }
 
 func (h S3Handler) UploadFileWithBytes(key string, data []byte, metadata map[string]*string) error {
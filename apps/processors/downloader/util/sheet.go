@@ -1,9 +1,7 @@
 package util
 
 import (
-	"bytes"
 	"fmt"
-	"log"
 	"strings"
 
 	excelize "github.com/xuri/excelize/v2"
@@ -24,22 +22,13 @@ func FormatSheetNameInRange(sheetName string) string {
 	return fmt.Sprintf("'%s'", strings.ReplaceAll(sheetName, "'", "''"))
 }
 
-func GetSheetNamesFromXlsxData(fileBytes []byte) []string {
-	var sheetNames []string
-
+func GetSheetNamesFromXlsxFile(filePath string) ([]string, error) {
 	// Open the Excel file
-	xlsx, err := excelize.OpenReader(bytes.NewReader(fileBytes))
+	xlsx, err := excelize.OpenFile(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("Error when opening xlsx file: %w", err)
 	}
+	defer xlsx.Close()
 
-	// Get all sheet names
-	sheetList := xlsx.GetSheetList()
-
-	// Iterate through sheet names and print them
-	for _, sheetName := range sheetList {
-		sheetNames = append(sheetNames, sheetName)
-	}
-
-	return sheetNames
-}
\ No newline at end of file
+	return xlsx.GetSheetList(), nil
+}
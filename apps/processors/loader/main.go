@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"loader/pkg/config"
+	"loader/pkg/job"
 	"loader/pkg/logging"
 	"loader/routers"
 	"loader/service/loader"
@@ -17,6 +18,7 @@ func init() {
 	config.Setup()
 	logging.Setup()
 	loader.Setup()
+	job.Setup()
 }
 
 func main() {
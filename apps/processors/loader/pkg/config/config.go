@@ -1,6 +1,8 @@
 package config
 
 import (
+	"shared/storage"
+
 	"github.com/caarlos0/env/v9"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +30,14 @@ type IAppConfig struct {
 	S3SecretKey      string `env:"S3_SECRET_KEY" envDefault:"abc123456"`
 	S3Ssl            bool   `env:"S3_SSL" envDefault:"false"`
 
+	// storage of the diff data bucket: s3, or local to keep the bucket in {STORAGE_LOCAL_DIR}/{S3_DIFF_DATA_BUCKET}
+	StorageBackend  string `env:"STORAGE_BACKEND" envDefault:"s3"`
+	StorageLocalDir string `env:"STORAGE_LOCAL_DIR" envDefault:"/var/lib/processors/storage"`
+
+	// Jobs
+	JobStore string `env:"JOB_STORE" envDefault:"memory"`
+	JobTtl   int    `env:"JOB_TTL" envDefault:"86400"` // seconds to keep finished jobs
+
 	// Dest DB
 	DbType     DbType `env:"DB_TYPE" envDefault:"postgres"`
 	DbUri      string `env:"DB_URI" envDefault:""`
@@ -54,3 +64,19 @@ func Setup() {
 		panic(err)
 	}
 }
+
+// StorageConfig is the storage of the diff data bucket
+func (c *IAppConfig) StorageConfig() storage.Config {
+	return storage.Config{
+		Backend:  c.StorageBackend,
+		Bucket:   c.S3DiffDataBucket,
+		LocalDir: c.StorageLocalDir,
+		S3: storage.S3Config{
+			Endpoint:  c.S3Endpoint,
+			Region:    c.S3Region,
+			AccessKey: c.S3AccessKey,
+			SecretKey: c.S3SecretKey,
+			Ssl:       c.S3Ssl,
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"loader/pkg/e"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+func (s Status) IsFinished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+type Job struct {
+	Id     string           `json:"id"`
+	Type   string           `json:"type"`
+	Status Status           `json:"status"`
+	Stage  string           `json:"stage,omitempty"`
+	Result interface{}      `json:"result,omitempty"`
+	Error  *e.ErrorResponse `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// RunFunc does the work of a job, its context is cancelled when the job is cancelled
+type RunFunc func(ctx context.Context) (interface{}, error)
+
+// #########################################################################################################
+
+type stageReporterKey struct{}
+
+type stageReporter func(stage string)
+
+// ReportStage records the progress stage of the job running with ctx, it does nothing outside of jobs
+func ReportStage(ctx context.Context, stage string) {
+	if report, ok := ctx.Value(stageReporterKey{}).(stageReporter); ok && report != nil {
+		report(stage)
+	}
+}
+
+// WithoutStageReport stops reporting the stages of work running with ctx, e.g. for concurrent work inside a job
+func WithoutStageReport(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stageReporterKey{}, stageReporter(nil))
+}
+
+func withStageReporter(ctx context.Context, report stageReporter) context.Context {
+	return context.WithValue(ctx, stageReporterKey{}, report)
+}
@@ -0,0 +1,150 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loader/pkg/config"
+	"loader/pkg/e"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Manager runs the jobs in background & keeps their state in the store
+type Manager struct {
+	store Store
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+var DefaultManager *Manager
+
+func NewManager(store Store) *Manager {
+	return &Manager{
+		store:   store,
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+func Setup() {
+	switch config.AppConfig.JobStore {
+	case "memory":
+		DefaultManager = NewManager(NewMemoryStore(time.Duration(config.AppConfig.JobTtl) * time.Second))
+	default:
+		log.Fatalf("Unsupported job store %s", config.AppConfig.JobStore)
+	}
+}
+
+func (m *Manager) Submit(ctx context.Context, jobType string, run RunFunc) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		Id:        uuid.New().String(),
+		Type:      jobType,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := m.store.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("Error when creating job: %w", err)
+	}
+
+	// the job outlives the request
+	runCtx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.cancels[job.Id] = cancel
+	m.mu.Unlock()
+
+	go m.run(runCtx, *job, run)
+
+	return job, nil
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	job, err := m.store.Get(ctx, id)
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, e.NewExternalErrorWithDescription(e.JOB_NOT_FOUND, "Job not found", fmt.Sprintf("Job %s not found", id))
+	}
+	return job, err
+}
+
+// Cancel stops the running job, the job is marked as cancelled when its work returns
+func (m *Manager) Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.IsFinished() {
+		return nil, e.NewExternalErrorWithDescription(e.JOB_FINISHED, "Job is finished", fmt.Sprintf("Job %s is %s", id, job.Status))
+	}
+
+	m.mu.Lock()
+	cancel, ok := m.cancels[id]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return job, nil
+}
+
+func (m *Manager) run(ctx context.Context, job Job, run RunFunc) {
+	logger := log.WithFields(log.Fields{"jobId": job.Id, "jobType": job.Type})
+	defer func() {
+		m.mu.Lock()
+		cancel := m.cancels[job.Id]
+		delete(m.cancels, job.Id)
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	var mu sync.Mutex
+	update := func(change func(job *Job)) {
+		mu.Lock()
+		defer mu.Unlock()
+		change(&job)
+		job.UpdatedAt = time.Now()
+		if err := m.store.Update(context.Background(), &job); err != nil {
+			logger.Error("Error when updating job: ", err)
+		}
+	}
+
+	update(func(job *Job) { job.Status = StatusRunning })
+	ctx = withStageReporter(ctx, func(stage string) {
+		update(func(job *Job) { job.Stage = stage })
+	})
+
+	result, err := runSafely(ctx, run)
+
+	update(func(job *Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		switch {
+		case ctx.Err() != nil:
+			job.Status = StatusCancelled
+		case err != nil:
+			_, errResponse := e.NewErrorResponse(err)
+			job.Status = StatusFailed
+			job.Error = &errResponse
+		default:
+			job.Status = StatusSucceeded
+			job.Result = result
+		}
+	})
+	if err != nil {
+		logger.Error("Job failed: ", err)
+	} else {
+		logger.Info("Job finished")
+	}
+}
+
+func runSafely(ctx context.Context, run RunFunc) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Job panicked: %v", r)
+		}
+	}()
+	return run(ctx)
+}
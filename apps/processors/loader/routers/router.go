@@ -27,5 +27,9 @@ func InitRouter() *gin.Engine {
 	apiV1.POST("/excel/load", v1.LoadSheet)
 	apiV1.POST("/google-sheets/load", v1.LoadSheet)
 
+	apiV1.POST("/jobs", v1.CreateJob)
+	apiV1.GET("/jobs/:id", v1.GetJob)
+	apiV1.DELETE("/jobs/:id", v1.CancelJob)
+
 	return r
 }
@@ -1,8 +1,10 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"loader/pkg/app"
+	"loader/service"
 	"loader/service/sheet"
 	"net/http"
 
@@ -34,7 +36,17 @@ func LoadSheet(c *gin.Context) {
 		return
 	}
 
-	service, err := sheet.NewService(sheet.SheetServiceInitParams{
+	result, err := loadSheet(c.Request.Context(), body)
+	if err != nil {
+		appG.Error(err)
+		return
+	}
+
+	appG.Response(http.StatusOK, *result)
+}
+
+func loadSheet(ctx context.Context, body LoadRequest) (*service.LoadedResult, error) {
+	sheetService, err := sheet.NewService(sheet.SheetServiceInitParams{
 		DataSourceId: body.DataSourceId,
 		SyncVersion:  *body.SyncVersion,
 		PrevVersion:  *body.PrevVersion,
@@ -42,17 +54,13 @@ func LoadSheet(c *gin.Context) {
 		Metadata:     body.Metadata,
 	})
 	if err != nil {
-		appG.Error(fmt.Errorf("Error when initializing excel service for ds %s: %w", body.DataSourceId, err))
-		return
+		return nil, fmt.Errorf("Error when initializing excel service for ds %s: %w", body.DataSourceId, err)
 	}
 
-	requestContext := c.Request.Context()
-	result, err := service.Load(requestContext)
+	result, err := sheetService.Load(ctx)
 	if err != nil {
 		log.Error(fmt.Sprintf("Error running load data for ds %s: ", body.DataSourceId), err)
-		appG.Error(fmt.Errorf("Error running load data for ds %s: %w", body.DataSourceId, err))
-		return
+		return nil, fmt.Errorf("Error running load data for ds %s: %w", body.DataSourceId, err)
 	}
-
-	appG.Response(http.StatusOK, *result)
+	return result, nil
 }
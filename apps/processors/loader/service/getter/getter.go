@@ -6,7 +6,7 @@ import (
 	sch "loader/libs/schema"
 	"loader/pkg/config"
 	"loader/service"
-	"loader/util/s3"
+	"shared/storage"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/samber/lo"
@@ -18,7 +18,7 @@ type GetterInitParams struct {
 	DataSourceId string      `json:"dataSourceId"`
 	SyncVersion  uint        `json:"syncVersion"`
 	PrevVersion  uint        `json:"prevVersion"`
-	Metadata    interface{} `json:"metadata"`
+	Metadata     interface{} `json:"metadata"`
 }
 
 type Getter struct {
@@ -28,8 +28,8 @@ type Getter struct {
 	prevVersion  uint
 	metadata     interface{}
 
-	// s3
-	s3DiffDataHandler *s3.S3Handler
+	// storage
+	diffDataStorage storage.Storage
 }
 
 func NewGetter(params GetterInitParams) (*Getter, error) {
@@ -39,19 +39,13 @@ func NewGetter(params GetterInitParams) (*Getter, error) {
 	g.prevVersion = params.PrevVersion
 	g.metadata = params.Metadata
 
-	// s3
-	handler, err := s3.NewHandlerWithConfig(&s3.S3HandlerConfig{
-		Endpoint:  config.AppConfig.S3Endpoint,
-		Region:    config.AppConfig.S3Region,
-		Bucket:    config.AppConfig.S3DiffDataBucket,
-		AccessKey: config.AppConfig.S3AccessKey,
-		SecretKey: config.AppConfig.S3SecretKey,
-	})
+	// storage
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
 	if err != nil {
-		log.Error("Error when initializing s3 handler: ", err)
+		log.Error("Error when initializing storage: ", err)
 		return nil, err
 	}
-	g.s3DiffDataHandler = handler
+	g.diffDataStorage = storageHandler
 
 	return &g, nil
 }
@@ -79,7 +73,7 @@ func (g *Getter) GetSchema() (sch.TableSchema, error) {
 	log.Info("Getting schema")
 	var schema sch.TableSchema
 	schemaFileKey := fmt.Sprintf("schema/%s-%d.json", g.dataSourceId, g.syncVersion)
-	schemaFile, err := g.s3DiffDataHandler.ReadFileByte(schemaFileKey)
+	schemaFile, err := g.diffDataStorage.Get(context.Background(), schemaFileKey)
 	if err != nil {
 		log.Error("Error when reading schema file: ", err)
 		return nil, err
@@ -120,7 +114,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 	// get added rows
 	group.Go(func() error {
 		var addedRows service.DiffResult
-		addedRowsFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultAddedRowsFileKey())
+		addedRowsFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultAddedRowsFileKey())
 		if err != nil {
 			log.Error("Error when reading added rows file: ", err)
 			return err
@@ -144,7 +138,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 		// get schema diff
 		group.Go(func() error {
 			var schemaDiff service.SchemaDiffResult
-			schemaDiffFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultSchemaFileKey())
+			schemaDiffFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultSchemaFileKey())
 			if err != nil {
 				log.Error("Error when reading schema diff file: ", err)
 				return err
@@ -161,7 +155,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 		// get deleted rows
 		group.Go(func() error {
 			var deletedRows service.DiffResult
-			deletedRowsFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultDeletedRowsFileKey())
+			deletedRowsFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultDeletedRowsFileKey())
 			if err != nil {
 				log.Error("Error when reading added rows file: ", err)
 				return err
@@ -181,7 +175,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 		// get update fields
 		group.Go(func() error {
 			var updatedFields service.DiffResult
-			updatedFieldsFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultUpdatedFieldsFileKey())
+			updatedFieldsFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultUpdatedFieldsFileKey())
 			if err != nil {
 				log.Error("Error when reading updated fields file: ", err)
 				return err
@@ -214,7 +208,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 		// get added fields
 		group.Go(func() error {
 			var addedFields service.DiffResult
-			addedFieldsFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultAddedFieldsFileKey())
+			addedFieldsFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultAddedFieldsFileKey())
 			if err != nil {
 				log.Error("Error when reading added fields file: ", err)
 				return err
@@ -247,7 +241,7 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 		// get deleted fields
 		group.Go(func() error {
 			var deletedFields service.DiffResult
-			deletedFieldsFile, err := g.s3DiffDataHandler.ReadFileByte(g.getS3ResultDeletedFieldsFileKey())
+			deletedFieldsFile, err := g.diffDataStorage.Get(ctx, g.getS3ResultDeletedFieldsFileKey())
 			if err != nil {
 				log.Error("Error when reading deleted fields file: ", err)
 				return err
@@ -281,8 +275,122 @@ func (g *Getter) GetLoadData() (*service.LoaderData, error) {
 	if err := group.Wait(); err != nil {
 		return &data, err
 	}
+	decodeArrayFields(&data)
+	data.CellMeta = extractRowMetadataField(&data, sch.HashedCellMetaField)
+	data.CellErrors = extractRowMetadataField(&data, sch.HashedCellErrorsField)
 
 	log.Info("Finish getting diff data")
 
 	return &data, nil
 }
+
+// decodeArrayFields parses the JSON array text of the Array fields, they are loaded as JSON arrays
+func decodeArrayFields(data *service.LoaderData) {
+	arrayFields := make(map[string]bool)
+	for fieldName, field := range data.Schema {
+		if field.Type == sch.Array {
+			arrayFields[fieldName] = true
+		}
+	}
+	if len(arrayFields) == 0 {
+		return
+	}
+
+	decode := func(value interface{}) interface{} {
+		text, ok := value.(string)
+		if !ok {
+			return value
+		}
+		var items []interface{}
+		if err := jsoniter.UnmarshalFromString(text, &items); err != nil {
+			return value
+		}
+		return items
+	}
+	for idx, fieldName := range data.AddedRows.Fields {
+		if !arrayFields[fieldName] {
+			continue
+		}
+		for _, row := range data.AddedRows.Rows {
+			row[idx] = decode(row[idx])
+		}
+	}
+	for _, rowData := range data.UpdatedFields {
+		for fieldName, value := range rowData {
+			if arrayFields[fieldName] {
+				rowData[fieldName] = decode(value)
+			}
+		}
+	}
+	for _, rowData := range data.AddedFields {
+		for fieldName, value := range rowData {
+			if arrayFields[fieldName] {
+				rowData[fieldName] = decode(value)
+			}
+		}
+	}
+}
+
+// extractRowMetadataField moves a field out of the row data, its values are loaded to the row metadata
+func extractRowMetadataField(data *service.LoaderData, field string) service.RowMetadataData {
+	result := service.RowMetadataData{Rows: make(map[string]interface{})}
+	delete(data.Schema, field)
+	delete(data.SchemaChanges.AddedFields, field)
+	delete(data.SchemaChanges.UpdatedFields, field)
+	delete(data.SchemaChanges.UpdatedTypeFields, field)
+	delete(data.SchemaChanges.KeptFields, field)
+	if lo.Contains(data.SchemaChanges.DeletedFields, field) {
+		data.SchemaChanges.DeletedFields = lo.Without(data.SchemaChanges.DeletedFields, field)
+		result.Deleted = true
+	}
+
+	decode := func(value interface{}) interface{} {
+		text, ok := value.(string)
+		if !ok || text == "" {
+			return nil
+		}
+		var decoded map[string]interface{}
+		if err := jsoniter.UnmarshalFromString(text, &decoded); err != nil || len(decoded) == 0 {
+			return nil
+		}
+		return decoded
+	}
+
+	if fieldIdx := lo.IndexOf(data.AddedRows.Fields, field); fieldIdx != -1 {
+		primaryIdx := lo.IndexOf(data.AddedRows.Fields, sch.HashedPrimaryField)
+		for idx, row := range data.AddedRows.Rows {
+			if value := decode(row[fieldIdx]); value != nil && primaryIdx != -1 {
+				if rowId, ok := row[primaryIdx].(string); ok {
+					result.Rows[rowId] = value
+				}
+			}
+			data.AddedRows.Rows[idx] = append(row[:fieldIdx:fieldIdx], row[fieldIdx+1:]...)
+		}
+		data.AddedRows.Fields = append(data.AddedRows.Fields[:fieldIdx:fieldIdx], data.AddedRows.Fields[fieldIdx+1:]...)
+	}
+	for _, rowFields := range []map[string]map[string]interface{}{data.UpdatedFields, data.AddedFields} {
+		for rowId, rowData := range rowFields {
+			value, ok := rowData[field]
+			if !ok {
+				continue
+			}
+			result.Rows[rowId] = decode(value)
+			delete(rowData, field)
+			if len(rowData) == 0 {
+				delete(rowFields, rowId)
+			}
+		}
+	}
+	for rowId, fields := range data.DeletedFields {
+		if !lo.Contains(fields, field) {
+			continue
+		}
+		result.Rows[rowId] = nil
+		if fields = lo.Without(fields, field); len(fields) == 0 {
+			delete(data.DeletedFields, rowId)
+		} else {
+			data.DeletedFields[rowId] = fields
+		}
+	}
+	return result
+}
@@ -12,6 +12,13 @@ type DeletedRowsData []string
 type UpdatedFieldsData map[string]map[string]interface{}
 type AddedFieldsData map[string]map[string]interface{}
 type DeletedFieldsData map[string][]string
+
+// RowMetadataData is a value of the row metadata by row id, nil when removed from the row
+type RowMetadataData struct {
+	Rows map[string]interface{}
+	// the field is no longer in the snapshot, the value is removed from all rows
+	Deleted bool
+}
 type LoaderData struct {
 	Schema       schema.TableSchema
 	PrimaryField string
@@ -25,6 +32,11 @@ type LoaderData struct {
 	UpdatedFields UpdatedFieldsData
 	DeletedFields DeletedFieldsData
 
+	// captured hyperlinks, notes & formulas
+	CellMeta RowMetadataData
+	// spreadsheet error kinds & cell references of the error values
+	CellErrors RowMetadataData
+
 	Metadata interface{}
 }
 
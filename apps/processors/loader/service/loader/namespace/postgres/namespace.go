@@ -16,6 +16,8 @@ const (
 	IsDeletedColumn TableColumn = "is_deleted"
 	MetadataColumn  TableColumn = "metadata"
 	HasErrorColumn  TableColumn = "has_error"
+	CellsColumn     TableColumn = "cells"
+	ErrorsColumn    TableColumn = "errors"
 
 	// data table column
 	TableDataDataColumn TableColumn = "data"
@@ -181,6 +181,12 @@ func formatVariableToPostgresStatementValue(a interface{}) string {
 		return r.Replace(strconv.Quote(v))
 	case nil:
 		return "null"
+	case []interface{}:
+		marshalled, err := jsoniter.MarshalToString(v)
+		if err != nil {
+			return "null"
+		}
+		return escapeSingleQuote(marshalled)
 	default:
 		return fmt.Sprintf("%v", v)
 	}
@@ -198,6 +204,25 @@ func formatEnumToPostgresStatementValue(enum []interface{}) (string, error) {
 	return escapeSingleQuote(marshalled), nil
 }
 
+// formatFieldMetadataToPostgresStatementValue returns the unit or currency code of the field as json, nil when it has none
+func formatFieldMetadataToPostgresStatementValue(field schema.FieldSchema) (*string, error) {
+	var metadata map[string]string
+	switch field.Type {
+	case schema.Currency:
+		metadata = map[string]string{"currency": field.Unit}
+	case schema.Percent, schema.Duration:
+		metadata = map[string]string{"unit": field.Unit}
+	default:
+		return nil, nil
+	}
+	marshalled, err := jsoniter.MarshalToString(metadata)
+	if err != nil {
+		log.Error("Error when marshaling field metadata: ", err)
+		return nil, err
+	}
+	return &marshalled, nil
+}
+
 // ***********
 
 type PostgreLoader struct {
@@ -306,7 +331,11 @@ func (l *PostgreLoader) Load(ctx context.Context, getter *getter.Getter) (*servi
 		if err != nil {
 			return nil, l.checkAndMappingError(err)
 		}
-		err = l.loadRowErrorMetadata(txn)
+		err = l.loadCellMetadata(txn, data)
+		if err != nil {
+			return nil, l.checkAndMappingError(err)
+		}
+		err = l.loadRowErrorMetadata(txn, data)
 		if err != nil {
 			return nil, l.checkAndMappingError(err)
 		}
@@ -337,7 +366,11 @@ func (l *PostgreLoader) Load(ctx context.Context, getter *getter.Getter) (*servi
 			if err != nil {
 				return nil, l.checkAndMappingError(err)
 			}
-			err = l.loadRowErrorMetadata(txn)
+			err = l.loadCellMetadata(txn, data)
+			if err != nil {
+				return nil, l.checkAndMappingError(err)
+			}
+			err = l.loadRowErrorMetadata(txn, data)
 			if err != nil {
 				return nil, l.checkAndMappingError(err)
 			}
@@ -591,6 +624,7 @@ func (l *PostgreLoader) loadSchema(txn *sql.Tx, data *service.LoaderData) error
 		name.EnumColumn,
 		name.ReadonlyColumn,
 		name.IsPrimaryColumn,
+		string(name.MetadataColumn),
 	)
 	// l.logger.Debug("Query: ", query)
 	stmt, err := txn.Prepare(query)
@@ -604,7 +638,11 @@ func (l *PostgreLoader) loadSchema(txn *sql.Tx, data *service.LoaderData) error
 		if err != nil {
 			return err
 		}
-		_, err = stmt.Exec(fieldId, schemaId, field.Name, field.Type, field.OriginalType, field.Nullable, enum, field.Readonly, field.Primary)
+		metadata, err := formatFieldMetadataToPostgresStatementValue(field)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.Exec(fieldId, schemaId, field.Name, field.Type, field.OriginalType, field.Nullable, enum, field.Readonly, field.Primary, metadata)
 		if err != nil {
 			l.logger.Debug("Error when inserting field: ", err)
 			return err
@@ -688,6 +726,7 @@ func (l *PostgreLoader) loadSchemaChange(txn *sql.Tx, data *service.LoaderData)
 			name.EnumColumn,
 			name.ReadonlyColumn,
 			name.IsPrimaryColumn,
+			string(name.MetadataColumn),
 		)
 		// l.logger.Debug("Query: ", query)
 		stmt, err := txn.Prepare(query)
@@ -701,7 +740,11 @@ func (l *PostgreLoader) loadSchemaChange(txn *sql.Tx, data *service.LoaderData)
 			if err != nil {
 				return err
 			}
-			_, err = stmt.Exec(fieldId, schemaId, field.Name, field.Type, field.OriginalType, field.Nullable, enum, field.Readonly, field.Primary)
+			metadata, err := formatFieldMetadataToPostgresStatementValue(field)
+			if err != nil {
+				return err
+			}
+			_, err = stmt.Exec(fieldId, schemaId, field.Name, field.Type, field.OriginalType, field.Nullable, enum, field.Readonly, field.Primary, metadata)
 			if err != nil {
 				l.logger.Debug("Error when inserting field: ", err)
 				return err
@@ -726,8 +769,16 @@ func (l *PostgreLoader) loadSchemaChange(txn *sql.Tx, data *service.LoaderData)
 			if err != nil {
 				return err
 			}
+			metadata, err := formatFieldMetadataToPostgresStatementValue(field)
+			if err != nil {
+				return err
+			}
+			metadataValue := "NULL"
+			if metadata != nil {
+				metadataValue = fmt.Sprintf("'%s'", escapeSingleQuote(*metadata))
+			}
 			query := fmt.Sprintf(
-				"UPDATE %s SET %s = '%s', %s = '%s', %s = '%s', %s = %t, %s = '%s', %s = %t, %s = %t, %s = %s WHERE %s = %d AND %s = '%s'",
+				"UPDATE %s SET %s = '%s', %s = '%s', %s = '%s', %s = %t, %s = '%s', %s = %t, %s = %t, %s = %s, %s = %s WHERE %s = %d AND %s = '%s'",
 				name.SchemaFieldTable,
 				name.NameColumn, field.Name,
 				name.TypeColumn, field.Type,
@@ -736,6 +787,7 @@ func (l *PostgreLoader) loadSchemaChange(txn *sql.Tx, data *service.LoaderData)
 				name.EnumColumn, enum,
 				name.ReadonlyColumn, field.Readonly,
 				name.IsPrimaryColumn, field.Primary,
+				name.MetadataColumn, metadataValue,
 				name.UpdatedAtColumn, "NOW()",
 				name.SchemaIdColumn, schemaId,
 				name.HashedNameColumn, fieldId,
@@ -775,19 +827,12 @@ func (l *PostgreLoader) loadAddedRows(txn *sql.Tx, data *service.LoaderData) err
 		for _, row := range data.AddedRows.Rows {
 			dataInsert := make(map[string]interface{}, len(data.AddedRows.Fields))
 			var insertRowObject PostgresAddRowData
-			isRowHasError := false
 			for index, field := range data.AddedRows.Fields {
 				fieldData := row[index]
 				if field == schema.HashedPrimaryField {
 					insertRowObject.RowId = fieldData.(string)
 				}
 				dataInsert[field] = fieldData
-				if fieldData == schema.ErrorValue && !isRowHasError {
-					isRowHasError = true
-				}
-			}
-			if isRowHasError {
-				l.addRowError(insertRowObject.RowId)
 			}
 			dataInsertJson, err := jsoniter.MarshalToString(dataInsert)
 			if err != nil {
@@ -886,7 +931,6 @@ func (l *PostgreLoader) loadUpdateFields(txn *sql.Tx, data *service.LoaderData)
 	}
 
 	for rowId, fieldUpdateData := range data.UpdatedFields {
-		isRowHasError := false
 		var jsonbSet string
 		updatedFields := lo.Keys(fieldUpdateData)
 		for i, fieldName := range updatedFields {
@@ -906,12 +950,6 @@ func (l *PostgreLoader) loadUpdateFields(txn *sql.Tx, data *service.LoaderData)
 					formatVariableToPostgresStatementValue(fieldData),
 				)
 			}
-			if fieldData == schema.ErrorValue && !isRowHasError {
-				isRowHasError = true
-			}
-		}
-		if isRowHasError {
-			l.addRowError(rowId)
 		}
 		query := fmt.Sprintf(
 			"UPDATE \"%s\" SET %s = %s, %s = %s WHERE %s = '%s'",
@@ -945,7 +983,6 @@ func (l *PostgreLoader) loadAddedFields(txn *sql.Tx, data *service.LoaderData) e
 	// queryGroup.SetLimit(10)
 
 	for rowId, fieldAddData := range data.AddedFields {
-		isRowHasError := false
 		var jsonbSet string
 		addedFields := lo.Keys(fieldAddData)
 		for i, fieldName := range addedFields {
@@ -965,12 +1002,6 @@ func (l *PostgreLoader) loadAddedFields(txn *sql.Tx, data *service.LoaderData) e
 					formatVariableToPostgresStatementValue(fieldData),
 				)
 			}
-			if fieldData == schema.ErrorValue && !isRowHasError {
-				isRowHasError = true
-			}
-		}
-		if isRowHasError {
-			l.addRowError(rowId)
 		}
 		query := fmt.Sprintf(
 			"UPDATE \"%s\" SET %s = %s, %s = %s WHERE %s = '%s'",
@@ -1030,9 +1061,16 @@ func (l *PostgreLoader) loadDeletedFields(txn *sql.Tx, data *service.LoaderData)
 	return nil
 }
 
-func (l *PostgreLoader) loadRowErrorMetadata(txn *sql.Tx) error {
+// loadRowErrorMetadata flags the rows with spreadsheet errors, the errors are read from the cell errors mask of the rows
+func (l *PostgreLoader) loadRowErrorMetadata(txn *sql.Tx, data *service.LoaderData) error {
 	l.logger.Info("Loading row error metadata")
 
+	for rowId, cellErrors := range data.CellErrors.Rows {
+		if cellErrors != nil {
+			l.addRowError(rowId)
+		}
+	}
+
 	if len(l.rowErrorMap) == 0 {
 		l.logger.Info("No new error metadata to load")
 		if l.PrevVersion == 0 {
@@ -1063,12 +1101,11 @@ func (l *PostgreLoader) loadRowErrorMetadata(txn *sql.Tx) error {
 	if l.PrevVersion != 0 {
 		// clean no longer error rows
 		query := fmt.Sprintf(
-			"UPDATE \"%s\" SET %[2]s = %[2]s - ARRAY['%[3]s'] WHERE (%[2]s->'%[3]s')::bool IS TRUE AND NOT EXISTS (SELECT 1 FROM jsonb_each_text(%[4]s) AS json_data WHERE json_data.value = '%[5]s')",
+			"UPDATE \"%s\" SET %[2]s = %[2]s - ARRAY['%[3]s'] WHERE (%[2]s->'%[3]s')::bool IS TRUE AND NOT %[2]s ? '%[4]s'",
 			l.tableName,
 			name.MetadataColumn,
 			name.HasErrorColumn,
-			name.TableDataDataColumn,
-			schema.ErrorValue,
+			name.ErrorsColumn,
 		)
 		// l.logger.Debug("Query: ", query)
 		_, err := txn.Exec(query)
@@ -1082,3 +1119,65 @@ func (l *PostgreLoader) loadRowErrorMetadata(txn *sql.Tx) error {
 
 	return nil
 }
+
+// loadCellMetadata writes the captured hyperlinks, notes & formulas and the spreadsheet errors of the rows to the row metadata
+func (l *PostgreLoader) loadCellMetadata(txn *sql.Tx, data *service.LoaderData) error {
+	l.logger.Info("Loading cell metadata")
+
+	err := l.loadRowMetadataKey(txn, name.CellsColumn, data.CellMeta)
+	if err != nil {
+		return err
+	}
+	err = l.loadRowMetadataKey(txn, name.ErrorsColumn, data.CellErrors)
+	if err != nil {
+		return err
+	}
+
+	l.logger.Info("Loaded cell metadata to postgres")
+
+	return nil
+}
+
+func (l *PostgreLoader) loadRowMetadataKey(txn *sql.Tx, key name.TableColumn, rowMetadata service.RowMetadataData) error {
+	if rowMetadata.Deleted {
+		query := fmt.Sprintf(
+			"UPDATE \"%s\" SET %[2]s = %[2]s - '%[3]s' WHERE %[2]s ? '%[3]s'",
+			l.tableName,
+			name.MetadataColumn,
+			key,
+		)
+		_, err := txn.Exec(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	for rowId, value := range rowMetadata.Rows {
+		metadataValue := fmt.Sprintf("%s - '%s'", name.MetadataColumn, key)
+		if value != nil {
+			valueJson, err := jsoniter.MarshalToString(value)
+			if err != nil {
+				return err
+			}
+			metadataValue = fmt.Sprintf(
+				"jsonb_set(coalesce(%s, '{}'), '{%s}', '%s'::jsonb)",
+				name.MetadataColumn,
+				key,
+				escapeSingleQuote(valueJson),
+			)
+		}
+		query := fmt.Sprintf(
+			"UPDATE \"%s\" SET %s = %s WHERE %s = '%s'",
+			l.tableName,
+			name.MetadataColumn, metadataValue,
+			name.IdColumn, rowId,
+		)
+		// l.logger.Debug("Query: ", query)
+		_, err := txn.Exec(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
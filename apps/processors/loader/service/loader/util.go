@@ -14,7 +14,11 @@ func IsDataChanged(data *service.LoaderData) bool {
 		len(data.DeletedRows) > 0 ||
 		len(data.AddedFields) > 0 ||
 		len(data.UpdatedFields) > 0 ||
-		len(data.DeletedFields) > 0
+		len(data.DeletedFields) > 0 ||
+		len(data.CellMeta.Rows) > 0 ||
+		data.CellMeta.Deleted ||
+		len(data.CellErrors.Rows) > 0 ||
+		data.CellErrors.Deleted
 }
 
 func CaculateLoadedResult(loadData *service.LoaderData) *service.LoadedResult {
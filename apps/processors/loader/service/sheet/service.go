@@ -3,10 +3,11 @@ package sheet
 import (
 	"context"
 	"loader/pkg/config"
+	"loader/pkg/job"
 	"loader/service"
 	"loader/service/getter"
 	"loader/service/loader"
-	"loader/util/s3"
+	"shared/storage"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -28,8 +29,8 @@ type SheetService struct {
 	tableName    string
 	metadata     interface{}
 
-	// s3
-	s3DiffDataHandler *s3.S3Handler
+	// storage
+	diffDataStorage storage.Storage
 }
 
 func NewService(params SheetServiceInitParams) (*SheetService, error) {
@@ -40,19 +41,13 @@ func NewService(params SheetServiceInitParams) (*SheetService, error) {
 	s.tableName = params.TableName
 	s.metadata = params.Metadata
 
-	// s3
-	handler, err := s3.NewHandlerWithConfig(&s3.S3HandlerConfig{
-		Endpoint:  config.AppConfig.S3Endpoint,
-		Region:    config.AppConfig.S3Region,
-		Bucket:    config.AppConfig.S3DiffDataBucket,
-		AccessKey: config.AppConfig.S3AccessKey,
-		SecretKey: config.AppConfig.S3SecretKey,
-	})
+	// storage
+	storageHandler, err := storage.New(config.AppConfig.StorageConfig())
 	if err != nil {
-		log.Error("Error when initializing s3 handler: ", err)
+		log.Error("Error when initializing storage: ", err)
 		return nil, err
 	}
-	s.s3DiffDataHandler = handler
+	s.diffDataStorage = storageHandler
 
 	return &s, nil
 }
@@ -61,6 +56,7 @@ func (s *SheetService) Load(ctx context.Context) (*service.LoadedResult, error)
 	log.Info("Start load data")
 
 	// create getter
+	job.ReportStage(ctx, "prepare")
 	getter, err := getter.NewGetter(getter.GetterInitParams{
 		DataSourceId: s.dataSourceId,
 		SyncVersion:  s.syncVersion,
@@ -84,6 +80,7 @@ func (s *SheetService) Load(ctx context.Context) (*service.LoadedResult, error)
 		return nil, err
 	}
 	defer loaderInstance.Close()
+	job.ReportStage(ctx, "load")
 	loadResult, err := loaderInstance.Load(ctx, getter)
 	if err != nil {
 		log.Error("Error when loading data: ", err)
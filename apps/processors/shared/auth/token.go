@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/astaxie/beego/validation"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/microsoft"
+)
+
+var ErrTokenRefresh = errors.New("Cannot refresh access token")
+
+type Provider string
+
+const (
+	GoogleProvider    Provider = "google"
+	MicrosoftProvider Provider = "microsoft"
+)
+
+// Config is the default oauth clients & the token broker key, each processor reads it from its env
+type Config struct {
+	GoogleClientId        string
+	GoogleClientSecret    string
+	MicrosoftClientId     string
+	MicrosoftClientSecret string
+	// "common" when empty
+	MicrosoftTenant   string
+	TokenBrokerApiKey string
+
+	// the client of the token requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// maps the refresh failures, they wrap ErrTokenRefresh when nil
+	RefreshError func(err error) error
+}
+
+// Credentials of the provider account, embedded in the requests.
+// Besides a plain access token, the caller can send a refresh token (with the oauth client, default from config)
+// or a token broker url, then the access token is refreshed when it expires during a long sync
+type Credentials struct {
+	AccessToken string `form:"accessToken"`
+	// when the access token expires, it is refreshed right away if omitted
+	AccessTokenExpiry time.Time `form:"accessTokenExpiry"`
+
+	RefreshToken string `form:"refreshToken"`
+	ClientId     string `form:"clientId"`
+	ClientSecret string `form:"clientSecret"`
+
+	// the broker returns a fresh access token of the account on GET, as a oauth2 token response
+	TokenBrokerUrl string `form:"tokenBrokerUrl"`
+}
+
+// Valid is called by beego validation on the requests embedding the credentials
+func (c *Credentials) Valid(v *validation.Validation) {
+	if c.AccessToken == "" && c.RefreshToken == "" && c.TokenBrokerUrl == "" {
+		v.SetError("AccessToken", "One of accessToken, refreshToken or tokenBrokerUrl is required")
+	}
+}
+
+func (c Credentials) CanRefresh() bool {
+	return c.RefreshToken != "" || c.TokenBrokerUrl != ""
+}
+
+// NewTokenSource returns a token source refreshing the access token when it expires,
+// refresh failures are mapped by config.RefreshError
+func NewTokenSource(ctx context.Context, config Config, provider Provider, credentials Credentials) oauth2.TokenSource {
+	var token *oauth2.Token
+	if credentials.AccessToken != "" {
+		token = &oauth2.Token{
+			AccessToken: credentials.AccessToken,
+			Expiry:      credentials.AccessTokenExpiry,
+		}
+		if credentials.CanRefresh() && token.Expiry.IsZero() {
+			token.Expiry = time.Now()
+		}
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	refreshError := config.RefreshError
+	if refreshError == nil {
+		refreshError = func(err error) error {
+			return fmt.Errorf("%w: %v", ErrTokenRefresh, err)
+		}
+	}
+
+	switch {
+	case credentials.RefreshToken != "":
+		if token == nil {
+			token = &oauth2.Token{}
+		}
+		token.RefreshToken = credentials.RefreshToken
+		oauthConfig := newOAuthConfig(config, provider, credentials)
+		return &refreshErrorTokenSource{source: oauthConfig.TokenSource(ctx, token), refreshError: refreshError}
+	case credentials.TokenBrokerUrl != "":
+		return &refreshErrorTokenSource{
+			source: oauth2.ReuseTokenSource(token, &brokerTokenSource{
+				ctx:       ctx,
+				client:    client,
+				brokerUrl: credentials.TokenBrokerUrl,
+				apiKey:    config.TokenBrokerApiKey,
+			}),
+			refreshError: refreshError,
+		}
+	default:
+		return oauth2.StaticTokenSource(token)
+	}
+}
+
+func newOAuthConfig(config Config, provider Provider, credentials Credentials) *oauth2.Config {
+	oauthConfig := &oauth2.Config{
+		ClientID:     credentials.ClientId,
+		ClientSecret: credentials.ClientSecret,
+	}
+	switch provider {
+	case MicrosoftProvider:
+		if oauthConfig.ClientID == "" {
+			oauthConfig.ClientID = config.MicrosoftClientId
+			oauthConfig.ClientSecret = config.MicrosoftClientSecret
+		}
+		tenant := config.MicrosoftTenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		oauthConfig.Endpoint = microsoft.AzureADEndpoint(tenant)
+	default:
+		if oauthConfig.ClientID == "" {
+			oauthConfig.ClientID = config.GoogleClientId
+			oauthConfig.ClientSecret = config.GoogleClientSecret
+		}
+		oauthConfig.Endpoint = google.Endpoint
+	}
+	return oauthConfig
+}
+
+type refreshErrorTokenSource struct {
+	source       oauth2.TokenSource
+	refreshError func(err error) error
+}
+
+func (s *refreshErrorTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, s.refreshError(err)
+	}
+	return token, nil
+}
+
+type brokerTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type brokerTokenSource struct {
+	ctx       context.Context
+	client    *http.Client
+	brokerUrl string
+	apiKey    string
+}
+
+func (s *brokerTokenSource) Token() (*oauth2.Token, error) {
+	req, err := http.NewRequestWithContext(s.ctx, "GET", s.brokerUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.apiKey != "" {
+		req.Header.Set("X-API-Key", s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error requesting token broker: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Token broker responded %s", resp.Status)
+	}
+
+	var response brokerTokenResponse
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("Error decoding token broker response: %w", err)
+	}
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("Token broker returned empty access token")
+	}
+
+	token := &oauth2.Token{
+		AccessToken: response.AccessToken,
+		TokenType:   response.TokenType,
+	}
+	if response.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego/validation"
+)
+
+// rewriteTransport sends the requests to the test server, whatever the host of the provider endpoint
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTokenServer serves the token response to the token & broker requests, counting them
+func newTokenServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32, *http.Client) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	return server, &requests, &http.Client{Transport: &rewriteTransport{target: target}}
+}
+
+func writeToken(w http.ResponseWriter, accessToken string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"access_token":"` + accessToken + `","token_type":"Bearer","expires_in":3600}`))
+}
+
+func TestTokenSourceRefreshToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     Provider
+		expiry       time.Time
+		wantToken    string
+		wantRequests int32
+	}{
+		{name: "valid token", provider: GoogleProvider, expiry: time.Now().Add(time.Hour), wantToken: "access", wantRequests: 0},
+		{name: "expired token", provider: GoogleProvider, expiry: time.Now().Add(-time.Minute), wantToken: "refreshed", wantRequests: 1},
+		{name: "token without expiry", provider: GoogleProvider, wantToken: "refreshed", wantRequests: 1},
+		{name: "expired microsoft token", provider: MicrosoftProvider, expiry: time.Now().Add(-time.Minute), wantToken: "refreshed", wantRequests: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var refreshToken, clientId, path string
+			_, requests, client := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				refreshToken, path = r.PostForm.Get("refresh_token"), r.URL.Path
+				// the client is sent in the basic auth or in the form
+				if clientId, _, _ = r.BasicAuth(); clientId == "" {
+					clientId = r.PostForm.Get("client_id")
+				}
+				writeToken(w, "refreshed")
+			})
+			config := Config{GoogleClientId: "google-client", MicrosoftClientId: "microsoft-client", HTTPClient: client}
+
+			source := NewTokenSource(context.Background(), config, test.provider, Credentials{
+				AccessToken:       "access",
+				AccessTokenExpiry: test.expiry,
+				RefreshToken:      "refresh",
+			})
+			token, err := source.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != test.wantToken || atomic.LoadInt32(requests) != test.wantRequests {
+				t.Errorf("got token %s after %d requests, want %s after %d", token.AccessToken, *requests, test.wantToken, test.wantRequests)
+			}
+			if test.wantRequests == 0 {
+				return
+			}
+			wantClient, wantPath := "google-client", "/token"
+			if test.provider == MicrosoftProvider {
+				wantClient, wantPath = "microsoft-client", "/common/oauth2/v2.0/token"
+			}
+			if refreshToken != "refresh" || clientId != wantClient || path != wantPath {
+				t.Errorf("got refresh request of %s with %q on %s, want %s with the refresh token on %s", clientId, refreshToken, path, wantClient, wantPath)
+			}
+		})
+	}
+}
+
+func TestTokenSourceBroker(t *testing.T) {
+	var apiKey string
+	_, requests, client := newTokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("X-API-Key")
+		writeToken(w, "brokered")
+	})
+	config := Config{TokenBrokerApiKey: "key", HTTPClient: client}
+	source := NewTokenSource(context.Background(), config, GoogleProvider, Credentials{
+		AccessToken:       "access",
+		AccessTokenExpiry: time.Now().Add(-time.Minute),
+		TokenBrokerUrl:    "http://broker/token",
+	})
+
+	for i := 0; i < 2; i++ {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != "brokered" || token.Expiry.Before(time.Now().Add(time.Minute)) {
+			t.Errorf("got token %+v, want the brokered token", token)
+		}
+	}
+	// the brokered token is reused until it expires
+	if *requests != 1 || apiKey != "key" {
+		t.Errorf("got %d broker requests with key %q, want 1 with the api key", *requests, apiKey)
+	}
+}
+
+func TestTokenSourceBrokerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "error status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+		{name: "invalid response", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("not json"))
+		}},
+		{name: "empty access token", handler: func(w http.ResponseWriter, r *http.Request) {
+			writeToken(w, "")
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, client := newTokenServer(t, test.handler)
+			credentials := Credentials{TokenBrokerUrl: "http://broker/token"}
+
+			_, err := NewTokenSource(context.Background(), Config{HTTPClient: client}, GoogleProvider, credentials).Token()
+			if !errors.Is(err, ErrTokenRefresh) {
+				t.Errorf("got error %v, want ErrTokenRefresh", err)
+			}
+
+			mappedErr := errors.New("mapped")
+			config := Config{HTTPClient: client, RefreshError: func(err error) error { return mappedErr }}
+			if _, err := NewTokenSource(context.Background(), config, GoogleProvider, credentials).Token(); err != mappedErr {
+				t.Errorf("got error %v, want the error of RefreshError", err)
+			}
+		})
+	}
+}
+
+func TestTokenSourceStatic(t *testing.T) {
+	token, err := NewTokenSource(context.Background(), Config{}, GoogleProvider, Credentials{
+		AccessToken:       "access",
+		AccessTokenExpiry: time.Now().Add(-time.Minute),
+	}).Token()
+	// without refresh token nor broker, the access token is used as is
+	if err != nil || token.AccessToken != "access" {
+		t.Errorf("got token %+v, %v, want the access token", token, err)
+	}
+}
+
+func TestCredentialsValid(t *testing.T) {
+	tests := []struct {
+		credentials Credentials
+		wantErr     bool
+	}{
+		{credentials: Credentials{}, wantErr: true},
+		{credentials: Credentials{AccessToken: "access"}},
+		{credentials: Credentials{RefreshToken: "refresh"}},
+		{credentials: Credentials{TokenBrokerUrl: "http://broker/token"}},
+	}
+	for _, test := range tests {
+		v := &validation.Validation{}
+		test.credentials.Valid(v)
+		if v.HasErrors() != test.wantErr {
+			t.Errorf("Valid(%+v) errors = %v, want error %v", test.credentials, v.Errors, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+module shared
+
+go 1.18
+
+require (
+	github.com/astaxie/beego v1.12.3
+	github.com/aws/aws-sdk-go v1.44.297
+	github.com/json-iterator/go v1.1.12
+	golang.org/x/oauth2 v0.11.0
+)
+
+require (
+	cloud.google.com/go/compute/metadata v0.2.3 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/shiena/ansicolor v0.0.0-20151119151921-a422bbe96644 // indirect
+)
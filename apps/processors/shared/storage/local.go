@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// directory of the metadata of the objects, as {key}.json
+const localMetadataDir = ".metadata"
+
+// LocalStorage stores the objects as files of a directory, for a single machine without object store
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage stores the objects of the bucket in {dir}/{bucket}
+func NewLocalStorage(dir string, bucket string) (*LocalStorage, error) {
+	root, err := filepath.Abs(filepath.Join(dir, bucket))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid storage directory %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("Error when creating storage directory %s: %w", root, err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (st *LocalStorage) Put(ctx context.Context, key string, data []byte, metadata map[string]*string) error {
+	writer, err := st.NewWriter(ctx, key, metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Abort(err)
+		return err
+	}
+	return writer.Close()
+}
+
+func (st *LocalStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	filePath, err := st.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, localError(key, err)
+	}
+	return data, nil
+}
+
+func (st *LocalStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	filePath, err := st.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, localError(key, err)
+	}
+	metadata, err := st.readMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		Metadata:     metadata,
+	}, nil
+}
+
+func (st *LocalStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	// only the directory of the prefix is walked
+	start := st.root
+	if dir := path.Dir(prefix); strings.HasSuffix(prefix, "/") {
+		start = filepath.Join(st.root, filepath.FromSlash(prefix))
+	} else if dir != "." {
+		start = filepath.Join(st.root, filepath.FromSlash(dir))
+	}
+	err := filepath.WalkDir(start, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// metadata & files being written
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(st.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error when listing objects with prefix %s: %w", prefix, err)
+	}
+	return objects, nil
+}
+
+func (st *LocalStorage) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		filePath, err := st.filePath(key)
+		if err != nil {
+			return err
+		}
+		for _, removed := range []string{filePath, st.metadataPath(key)} {
+			if err := os.Remove(removed); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("Error when deleting object %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (st *LocalStorage) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := st.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, localError(key, err)
+	}
+	return file, nil
+}
+
+// NewWriter writes the object to a temp file, renamed to the object on Close so that readers never see a partial object
+func (st *LocalStorage) NewWriter(ctx context.Context, key string, metadata map[string]*string) (Writer, error) {
+	filePath, err := st.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return nil, fmt.Errorf("Error when creating directory of object %s: %w", key, err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("Error when creating object %s: %w", key, err)
+	}
+	return &localWriter{storage: st, key: key, filePath: filePath, file: file, metadata: metadata}, nil
+}
+
+// Location is the absolute path of the object, its directory is created so that external tools can write it
+func (st *LocalStorage) Location(key string) (string, error) {
+	filePath, err := st.filePath(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return "", fmt.Errorf("Error when creating directory of object %s: %w", key, err)
+	}
+	return filePath, nil
+}
+
+// filePath is the file of the key, keys escaping the storage directory are rejected
+func (st *LocalStorage) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned != "/"+key || key == localMetadataDir || strings.HasPrefix(key, localMetadataDir+"/") {
+		return "", fmt.Errorf("Invalid object key %s", key)
+	}
+	return filepath.Join(st.root, filepath.FromSlash(key)), nil
+}
+
+func (st *LocalStorage) metadataPath(key string) string {
+	return filepath.Join(st.root, localMetadataDir, filepath.FromSlash(key)+".json")
+}
+
+func (st *LocalStorage) readMetadata(key string) (map[string]*string, error) {
+	data, err := os.ReadFile(st.metadataPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]*string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error when reading metadata of object %s: %w", key, err)
+	}
+	var metadata map[string]*string
+	if err := jsoniter.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("Error when unmarshalling metadata of object %s: %w", key, err)
+	}
+	return metadata, nil
+}
+
+func (st *LocalStorage) writeMetadata(key string, metadata map[string]*string) error {
+	metadataPath := st.metadataPath(key)
+	if len(metadata) == 0 {
+		if err := os.Remove(metadataPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("Error when deleting metadata of object %s: %w", key, err)
+		}
+		return nil
+	}
+	data, err := jsoniter.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("Error when marshalling metadata of object %s: %w", key, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(metadataPath), 0o755); err != nil {
+		return fmt.Errorf("Error when creating metadata directory of object %s: %w", key, err)
+	}
+	return os.WriteFile(metadataPath, data, 0o644)
+}
+
+type localWriter struct {
+	storage  *LocalStorage
+	key      string
+	filePath string
+	file     *os.File
+	metadata map[string]*string
+}
+
+func (w *localWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+func (w *localWriter) Close() error {
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("Error when writing object %s: %w", w.key, err)
+	}
+	if err := w.storage.writeMetadata(w.key, w.metadata); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	if err := os.Rename(w.file.Name(), w.filePath); err != nil {
+		os.Remove(w.file.Name())
+		return fmt.Errorf("Error when writing object %s: %w", w.key, err)
+	}
+	return nil
+}
+
+func (w *localWriter) Abort(err error) {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func localError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return fmt.Errorf("Error when reading object %s: %w", key, err)
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func newTestStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	st, err := NewLocalStorage(t.TempDir(), "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestLocalStorageFilePath(t *testing.T) {
+	st := newTestStorage(t)
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "data/ds1-1.parquet"},
+		{key: "ds1.json"},
+		{key: "a/.metadata/b.json"},
+		{key: "", wantErr: true},
+		{key: "../escape", wantErr: true},
+		{key: "data/../../escape", wantErr: true},
+		{key: "data/../schema/ds1-1.json", wantErr: true},
+		{key: "/data/ds1-1.parquet", wantErr: true},
+		{key: "data//ds1-1.parquet", wantErr: true},
+		{key: "data/", wantErr: true},
+		{key: "./data", wantErr: true},
+		{key: ".metadata", wantErr: true},
+		{key: ".metadata/data/ds1-1.parquet.json", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			filePath, err := st.filePath(test.key)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("filePath(%q) error = %v, want error %v", test.key, err, test.wantErr)
+			}
+			if err == nil && filepath.Dir(filePath) != filepath.Join(st.root, filepath.Dir(filepath.FromSlash(test.key))) {
+				t.Errorf("filePath(%q) = %s, want a file of %s", test.key, filePath, st.root)
+			}
+		})
+	}
+}
+
+func TestLocalStoragePutGet(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStorage(t)
+	version := "3"
+	if err := st.Put(ctx, "data/ds1-3.parquet", []byte("data"), map[string]*string{"version": &version}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := st.Get(ctx, "data/ds1-3.parquet")
+	if err != nil || string(data) != "data" {
+		t.Fatalf("Get() = %q, %v, want the data", data, err)
+	}
+	info, err := st.Stat(ctx, "data/ds1-3.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 4 || info.Metadata["version"] == nil || *info.Metadata["version"] != "3" {
+		t.Errorf("Stat() = %+v, want the size & the metadata", info)
+	}
+
+	// the metadata is replaced on overwrite
+	if err := st.Put(ctx, "data/ds1-3.parquet", []byte("new data"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := st.Stat(ctx, "data/ds1-3.parquet"); err != nil || len(info.Metadata) != 0 || info.Size != 8 {
+		t.Errorf("Stat() after overwrite = %+v, %v, want no metadata", info, err)
+	}
+
+	for name, call := range map[string]func() error{
+		"get":    func() error { _, err := st.Get(ctx, "data/missing.parquet"); return err },
+		"stat":   func() error { _, err := st.Stat(ctx, "data/missing.parquet"); return err },
+		"reader": func() error { _, err := st.NewReader(ctx, "data/missing.parquet"); return err },
+	} {
+		if err := call(); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s of a missing object: got error %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestLocalStorageWriter(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStorage(t)
+
+	writer, err := st.NewWriter(ctx, "data/ds1-1.parquet", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Stat(ctx, "data/ds1-1.parquet"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("object visible before Close: %v", err)
+	}
+	writer.Abort(errors.New("failure"))
+	if _, err := st.Stat(ctx, "data/ds1-1.parquet"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("object stored after Abort: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(st.root, "data"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("got files %v after Abort, want none", entries)
+	}
+}
+
+func TestLocalStorageListDelete(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStorage(t)
+	tag := "tag"
+	for _, key := range []string{"data/ds1-1.parquet", "data/ds1-2.parquet", "data/ds10-1.parquet", "schema/ds1-1.json", "result/ds1/1.json", "root.json"} {
+		if err := st.Put(ctx, key, []byte(key), map[string]*string{"tag": &tag}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an object being written is not listed
+	writer, err := st.NewWriter(ctx, "data/ds1-3.parquet", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Abort(nil)
+
+	list := func(prefix string) []string {
+		t.Helper()
+		infos, err := st.List(ctx, prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := make([]string, 0, len(infos))
+		for _, info := range infos {
+			keys = append(keys, info.Key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "data/ds1-", want: []string{"data/ds1-1.parquet", "data/ds1-2.parquet"}},
+		{prefix: "data/", want: []string{"data/ds1-1.parquet", "data/ds1-2.parquet", "data/ds10-1.parquet"}},
+		{prefix: "result/", want: []string{"result/ds1/1.json"}},
+		{prefix: "", want: []string{"data/ds1-1.parquet", "data/ds1-2.parquet", "data/ds10-1.parquet", "result/ds1/1.json", "root.json", "schema/ds1-1.json"}},
+		{prefix: "missing/", want: []string{}},
+	}
+	for _, test := range tests {
+		if got := list(test.prefix); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("List(%q) = %v, want %v", test.prefix, got, test.want)
+		}
+	}
+
+	if err := st.Delete(ctx, []string{"data/ds1-1.parquet", "data/missing.parquet"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := list("data/ds1-"); !reflect.DeepEqual(got, []string{"data/ds1-2.parquet"}) {
+		t.Errorf("List() after Delete = %v", got)
+	}
+	if _, err := os.Stat(st.metadataPath("data/ds1-1.parquet")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("metadata of the deleted object is kept: %v", err)
+	}
+	if err := st.Delete(ctx, []string{"../escape"}); err == nil {
+		t.Error("Delete of an invalid key: got no error")
+	}
+}
+
+func TestUploadDownloadFile(t *testing.T) {
+	ctx := context.Background()
+	st := newTestStorage(t)
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.csv")
+	if err := os.WriteFile(source, []byte("a,b\n1,2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UploadFile(ctx, st, "data/ds1-1.csv", source, nil); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target.csv")
+	if err := DownloadFile(ctx, st, "data/ds1-1.csv", target); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "a,b\n1,2\n" {
+		t.Errorf("downloaded %q, %v, want the uploaded file", data, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	st, err := New(Config{Backend: LocalBackend, LocalDir: dir, Bucket: "bucket"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := st.Location("data/ds1-1.parquet")
+	if err != nil || location != filepath.Join(dir, "bucket", "data", "ds1-1.parquet") {
+		t.Errorf("Location() = %s, %v, want the file in the bucket directory", location, err)
+	}
+	if _, err := New(Config{Backend: "ftp"}); err == nil {
+		t.Error("New() of an unknown backend: got no error")
+	}
+}
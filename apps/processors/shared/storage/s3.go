@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const (
+	// S3 deletes at most 1000 keys by request
+	s3DeleteBatchSize = 1000
+
+	s3DefaultAcl        = "private"
+	s3UploadPartSize    = 16 * 1024 * 1024
+	s3UploadConcurrency = 4
+)
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Ssl       bool
+}
+
+type S3Storage struct {
+	session  *session.Session
+	client   *awss3.S3
+	bucket   string
+	endpoint string
+}
+
+func NewS3Storage(config S3Config, bucket string) (*S3Storage, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(config.Endpoint),
+		Region:           aws.String(config.Region),
+		Credentials:      credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(!config.Ssl),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error when creating s3 session: %w", err)
+	}
+	return &S3Storage{
+		session:  sess,
+		client:   awss3.New(sess),
+		bucket:   bucket,
+		endpoint: config.Endpoint,
+	}, nil
+}
+
+func (st *S3Storage) Put(ctx context.Context, key string, data []byte, metadata map[string]*string) error {
+	writer, err := st.NewWriter(ctx, key, metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		writer.Abort(err)
+		return err
+	}
+	return writer.Close()
+}
+
+func (st *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	reader, err := st.NewReader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Error when reading object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+func (st *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	result, err := st.client.HeadObjectWithContext(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(st.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(key, err)
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.Int64Value(result.ContentLength),
+		LastModified: aws.TimeValue(result.LastModified),
+		Metadata:     result.Metadata,
+	}, nil
+}
+
+func (st *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	err := st.client.ListObjectsV2PagesWithContext(ctx, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(st.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.StringValue(object.Key),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error when listing objects with prefix %s: %w", prefix, err)
+	}
+	return objects, nil
+}
+
+func (st *S3Storage) Delete(ctx context.Context, keys []string) error {
+	for start := 0; start < len(keys); start += s3DeleteBatchSize {
+		end := start + s3DeleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objects := make([]*awss3.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, &awss3.ObjectIdentifier{Key: aws.String(key)})
+		}
+		result, err := st.client.DeleteObjectsWithContext(ctx, &awss3.DeleteObjectsInput{
+			Bucket: aws.String(st.bucket),
+			Delete: &awss3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("Error when deleting objects: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("Error when deleting object %s: %s", aws.StringValue(result.Errors[0].Key), aws.StringValue(result.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+func (st *S3Storage) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := st.client.GetObjectWithContext(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(st.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(key, err)
+	}
+	return result.Body, nil
+}
+
+// NewWriter uploads the object with multipart upload while it is written
+func (st *S3Storage) NewWriter(ctx context.Context, key string, metadata map[string]*string) (Writer, error) {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		uploader := s3manager.NewUploader(st.session, func(u *s3manager.Uploader) {
+			u.PartSize = s3UploadPartSize
+			u.Concurrency = s3UploadConcurrency
+		})
+		_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket:   aws.String(st.bucket),
+			Key:      aws.String(key),
+			ACL:      aws.String(s3DefaultAcl),
+			Body:     reader,
+			Metadata: metadata,
+		})
+		// unblock the writer when the upload fails before reading everything
+		reader.CloseWithError(err)
+		if err != nil {
+			err = fmt.Errorf("Error when upload file to s3: %w", err)
+		}
+		done <- err
+	}()
+	return &s3Writer{pipe: writer, done: done}, nil
+}
+
+func (st *S3Storage) Location(key string) (string, error) {
+	return fmt.Sprintf("%s/%s/%s", st.endpoint, st.bucket, key), nil
+}
+
+type s3Writer struct {
+	pipe *io.PipeWriter
+	done chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pipe.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	w.pipe.Close()
+	return <-w.done
+}
+
+// Abort fails the upload, the parts already uploaded are deleted by the uploader
+func (w *s3Writer) Abort(err error) {
+	w.pipe.CloseWithError(err)
+	<-w.done
+}
+
+func s3Error(key string, err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == awss3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return fmt.Errorf("Error when reading object %s: %w", key, err)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// backends of the storage, selected by STORAGE_BACKEND
+const (
+	S3Backend    = "s3"
+	LocalBackend = "local"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	// user metadata, only returned by Stat
+	Metadata map[string]*string
+}
+
+// Storage is the object store shared by the downloader, the comparer & the loader.
+// Keys are slash separated, e.g. data/{dataSourceId}-{syncVersion}.parquet.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, metadata map[string]*string) error
+	// Get returns ErrNotFound when the object does not exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Stat returns ErrNotFound when the object does not exist
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List lists the objects of the keys starting with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete deletes the objects, missing objects are ignored
+	Delete(ctx context.Context, keys []string) error
+
+	// NewReader streams the object, the caller closes the reader
+	NewReader(ctx context.Context, key string) (io.ReadCloser, error)
+	// NewWriter streams the object, the caller closes or aborts the writer
+	NewWriter(ctx context.Context, key string, metadata map[string]*string) (Writer, error)
+
+	// Location is the location of the object read & written by external tools, an URL or a file path
+	Location(key string) (string, error)
+}
+
+// Writer streams an object, the object is stored by Close & discarded by Abort
+type Writer interface {
+	io.WriteCloser
+	Abort(err error)
+}
+
+// Config selects the backend of the storage, each processor reads it from its env
+type Config struct {
+	Backend string
+	Bucket  string
+	// the local backend keeps the bucket in {LocalDir}/{Bucket}
+	LocalDir string
+	S3       S3Config
+}
+
+// New returns the storage of the bucket selected by the config
+func New(config Config) (Storage, error) {
+	switch config.Backend {
+	case S3Backend:
+		return NewS3Storage(config.S3, config.Bucket)
+	case LocalBackend:
+		return NewLocalStorage(config.LocalDir, config.Bucket)
+	default:
+		return nil, fmt.Errorf("Unknown storage backend %s, expected %s or %s", config.Backend, S3Backend, LocalBackend)
+	}
+}
+
+// UploadFile streams the file to the object
+func UploadFile(ctx context.Context, storage Storage, key string, filePath string, metadata map[string]*string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("Error when opening file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	writer, err := storage.NewWriter(ctx, key, metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, file); err != nil {
+		writer.Abort(err)
+		return fmt.Errorf("Error when uploading file %s: %w", filePath, err)
+	}
+	return writer.Close()
+}
+
+// DownloadFile streams the object to the file
+func DownloadFile(ctx context.Context, storage Storage, key string, filePath string) error {
+	reader, err := storage.NewReader(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Error when creating file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, reader); err != nil {
+		return fmt.Errorf("Error when downloading %s: %w", key, err)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ require (
 	golang.org/x/oauth2 v0.12.0
 	golang.org/x/sync v0.3.0
 	google.golang.org/api v0.138.0
+	shared v0.0.0
 )
 
 require (
@@ -65,3 +66,6 @@ require (
 	google.golang.org/protobuf v1.31.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// processor packages shared with the downloader
+replace shared => ../../../apps/processors/shared
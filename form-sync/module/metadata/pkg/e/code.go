@@ -8,5 +8,6 @@ const (
 
 	ERROR_EXIST_TAG = 10001
 
-	UPDATE_ERROR = 10002
+	UPDATE_ERROR        = 10002
+	TOKEN_REFRESH_ERROR = 10003
 )
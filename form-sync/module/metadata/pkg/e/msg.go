@@ -7,7 +7,8 @@ var MsgFlags = map[int]string{
 	ERROR_EXIST_TAG: "tag is exist",
 	NOT_FOUND:       "not found",
 
-	UPDATE_ERROR: "update error",
+	UPDATE_ERROR:        "update error",
+	TOKEN_REFRESH_ERROR: "cannot refresh access token",
 }
 
 // GetMsg get error information based on Code
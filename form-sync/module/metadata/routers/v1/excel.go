@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"metadata/pkg/app"
 	"metadata/pkg/e"
 	"metadata/service/excel"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -15,7 +17,7 @@ type UpdateExcelRequest struct {
 	WorkbookId  string `form:"workbookId" valid:"Required"`
 	WorksheetId string `form:"worksheetId" valid:"Required"`
 	// WorksheetName string `form:"worksheetName" valid:"Required"`
-	AccessToken  string `form:"accessToken" valid:"Required"`
+	auth.Credentials
 	DataSourceId string `form:"dataSourceId" valid:"Required"`
 }
 
@@ -39,7 +41,7 @@ func UpdateExcelMetaData(c *gin.Context) {
 		DriveId:      body.DriveId,
 		WorkbookId:   body.WorkbookId,
 		WorksheetId:  body.WorksheetId,
-		AccessToken:  body.AccessToken,
+		Credentials:  body.Credentials,
 		DataSourceId: body.DataSourceId,
 	})
 
@@ -47,6 +49,10 @@ func UpdateExcelMetaData(c *gin.Context) {
 	err := excelService.UpdateMetadata(requestContext)
 	if err != nil {
 		log.Error(fmt.Sprintf("Error running update metadata excel for ds %s: ", body.DataSourceId), err)
+		if errors.Is(err, auth.ErrTokenRefresh) {
+			appG.Response(e.ERROR, e.TOKEN_REFRESH_ERROR, nil)
+			return
+		}
 		appG.Response(e.ERROR, e.UPDATE_ERROR, nil)
 		return
 	}
@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"metadata/pkg/app"
 	"metadata/pkg/e"
 	google_sheets "metadata/service/google-sheets"
+	"shared/auth"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -13,8 +15,8 @@ import (
 type UpdateGoogleSheetsRequest struct {
 	SpreadsheetId string `form:"spreadsheetId" valid:"Required"`
 	SheetId       string `form:"sheetId" valid:"Required"`
-	AccessToken   string `form:"accessToken" valid:"Required"`
-	DataSourceId  string `form:"dataSourceId" valid:"Required"`
+	auth.Credentials
+	DataSourceId string `form:"dataSourceId" valid:"Required"`
 }
 
 type UpdateGoogleSheetsResponse struct {
@@ -36,7 +38,7 @@ func UpdateGoogleSheetsMetaData(c *gin.Context) {
 	service := google_sheets.New(google_sheets.GoogleSheetsServiceInitParams{
 		SpreadSheetId: body.SpreadsheetId,
 		SheetId:       body.SheetId,
-		AccessToken:   body.AccessToken,
+		Credentials:   body.Credentials,
 		DataSourceId:  body.DataSourceId,
 	})
 
@@ -44,6 +46,10 @@ func UpdateGoogleSheetsMetaData(c *gin.Context) {
 	err := service.UpdateMetadata(requestContext)
 	if err != nil {
 		log.Error(fmt.Sprintf("Error running update metadata for ds %s: ", body.DataSourceId), err)
+		if errors.Is(err, auth.ErrTokenRefresh) {
+			appG.Response(e.ERROR, e.TOKEN_REFRESH_ERROR, nil)
+			return
+		}
 		appG.Response(e.ERROR, e.UPDATE_ERROR, nil)
 		return
 	}
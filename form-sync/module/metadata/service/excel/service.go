@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"shared/auth"
 	"strconv"
 
 	jsoniter "github.com/json-iterator/go"
@@ -19,6 +20,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/oauth2"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -35,8 +37,8 @@ type MicrosoftExcelServiceInitParams struct {
 	WorksheetName string `json:"worksheetName"`
 
 	// auth
-	AccessToken string `json:"accessToken"`
-	SessionId   string `json:"sessionId"`
+	Credentials auth.Credentials `json:"credentials"`
+	SessionId   string           `json:"sessionId"`
 
 	DataSourceId string `json:"dataSourceId"`
 }
@@ -50,7 +52,8 @@ type MicrosoftExcelService struct {
 	worksheetName string
 
 	// auth
-	accessToken string
+	tokenSource oauth2.TokenSource
+	accessToken string // current access token, passed to the script
 	sessionId   string
 
 	// data
@@ -66,7 +69,7 @@ type MicrosoftExcelService struct {
 	dbPath string
 
 	// client
-	httpClient http.Client
+	httpClient *http.Client
 
 	// loger
 	logger *log.Entry
@@ -85,19 +88,18 @@ func New(params MicrosoftExcelServiceInitParams) *MicrosoftExcelService {
 		"dataSourceId": params.DataSourceId,
 	})
 
-	// client
-	client := &http.Client{}
+	tokenSource := auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.MicrosoftProvider, params.Credentials)
 
 	return &MicrosoftExcelService{
 		driveId:       params.DriveId,
 		workbookId:    params.WorkbookId,
 		worksheetId:   params.WorksheetId,
 		worksheetName: params.WorksheetName,
-		accessToken:   params.AccessToken,
+		tokenSource:   tokenSource,
 		sessionId:     params.SessionId,
 		dataSourceId:  params.DataSourceId,
 		dbPath:        config.AppConfig.DbPath,
-		httpClient:    *client,
+		httpClient:    oauth2.NewClient(context.Background(), tokenSource),
 		logger:        loggerEntry,
 	}
 }
@@ -118,7 +120,6 @@ func (s *MicrosoftExcelService) CreateSessionId(persistChanges bool) error {
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(bodyJSON)))
 	req.Header.Set("workbook-session-id", s.sessionId)
@@ -155,7 +156,6 @@ func (s *MicrosoftExcelService) GetWorksheetInfo() error {
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
 	req.Header.Set("workbook-session-id", s.sessionId)
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -179,7 +179,7 @@ func (s *MicrosoftExcelService) GetWorksheetInfo() error {
 }
 
 func (s *MicrosoftExcelService) UpdateMetadata(ctx context.Context) error {
-	log.Debug("Start updating metadata for %s", s.dataSourceId)
+	log.Debugf("Start updating metadata for %s", s.dataSourceId)
 
 	if err := s.CreateSessionId(true); err != nil {
 		s.logger.Error("Error creating session id", err)
@@ -202,6 +202,13 @@ func (s *MicrosoftExcelService) UpdateMetadata(ctx context.Context) error {
 	}
 	s.metadataFilePath = metaDataFilePath
 
+	// the script reads with a plain access token, take a fresh one
+	token, err := s.tokenSource.Token()
+	if err != nil {
+		return err
+	}
+	s.accessToken = token.AccessToken
+
 	// download metadata
 	s.DownloadMetadata(ctx)
 
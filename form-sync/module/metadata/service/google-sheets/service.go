@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"shared/auth"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
@@ -33,7 +34,7 @@ type GoogleSheetsServiceInitParams struct {
 	SheetId       string `json:"sheetId"`
 
 	// auth
-	AccessToken string `json:"accessToken"`
+	Credentials auth.Credentials `json:"credentials"`
 
 	DataSourceId string `json:"dataSourceId"`
 }
@@ -45,7 +46,8 @@ type GoogleSheetsService struct {
 	sheetId       string
 
 	// auth
-	accessToken string
+	tokenSource oauth2.TokenSource
+	accessToken string // current access token, passed to the script
 
 	// data
 	dataSourceId string
@@ -80,14 +82,14 @@ func New(params GoogleSheetsServiceInitParams) *GoogleSheetsService {
 	return &GoogleSheetsService{
 		spreadsheetId: params.SpreadSheetId,
 		sheetId:       params.SheetId,
-		accessToken:   params.AccessToken,
+		tokenSource:   auth.NewTokenSource(context.Background(), config.AppConfig.AuthConfig(), auth.GoogleProvider, params.Credentials),
 		dataSourceId:  params.DataSourceId,
 		logger:        loggerEntry,
 	}
 }
 
 func (s *GoogleSheetsService) UpdateMetadata(ctx context.Context) error {
-	log.Debug("Start updating metadata for %s", s.dataSourceId)
+	log.Debugf("Start updating metadata for %s", s.dataSourceId)
 
 	if err := s.GetDownloadUrl(ctx); err != nil {
 		s.logger.Error("Error getting download url", err)
@@ -106,6 +108,13 @@ func (s *GoogleSheetsService) UpdateMetadata(ctx context.Context) error {
 	}
 	s.metadataFilePath = metaDataFilePath
 
+	// the script reads with a plain access token, take a fresh one
+	token, err := s.tokenSource.Token()
+	if err != nil {
+		return err
+	}
+	s.accessToken = token.AccessToken
+
 	// download metadata
 	s.DownloadMetadata(ctx)
 
@@ -293,13 +302,7 @@ func (s *GoogleSheetsService) UploadRowMetadata(ctx context.Context) error {
 }
 
 func (s *GoogleSheetsService) GetDownloadUrl(ctx context.Context) error {
-	token := oauth2.Token{
-		AccessToken: s.accessToken,
-		// Expiry:      time.Now().Add(-30 * time.Minute),
-	}
-	tokenSource := oauth2.StaticTokenSource(&token)
-
-	driveService, err := drive.NewService(ctx, option.WithTokenSource(tokenSource))
+	driveService, err := drive.NewService(ctx, option.WithTokenSource(s.tokenSource))
 	if err != nil {
 		return err
 	}